@@ -0,0 +1,142 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"sync"
+)
+
+// Checkpoint records which batches of a dataset file have been committed, so
+// an interrupted load can resume where it stopped. It is only valid for the
+// exact source file, batch size and load mode it was written for, and outside
+// upsert mode for the xidmap holding the UIDs of the nodes already created.
+type Checkpoint struct {
+	Source    string  `json:"source"`
+	Checksum  string  `json:"sha256"`
+	BatchSize int     `json:"batch_size"`
+	Upsert    bool    `json:"upsert"`
+	XIDMap    string  `json:"xidmap,omitempty"`    // absolute path of the xidmap of the load
+	Next      int64   `json:"next"`                // every batch before Next is committed
+	Committed []int64 `json:"committed,omitempty"` // committed batches after Next
+
+	path string
+	mu   sync.Mutex
+	done map[int64]bool
+}
+
+// CheckpointPath returns the checkpoint file kept next to a dataset file.
+func CheckpointPath(source string) string {
+	return source + ".checkpoint"
+}
+
+// NewCheckpoint returns an empty checkpoint for the dataset file source,
+// loaded with the xidmap file xidMap, if any.
+func NewCheckpoint(source, checksum string, opts LoadOptions, xidMap string) *Checkpoint {
+	return &Checkpoint{
+		Source:    source,
+		Checksum:  checksum,
+		BatchSize: opts.BatchSize,
+		Upsert:    opts.Upsert,
+		XIDMap:    xidMap,
+		path:      CheckpointPath(source),
+		done:      make(map[int64]bool),
+	}
+}
+
+// LoadCheckpoint reads the checkpoint of the dataset file source and checks
+// that it was written for the same file content, options and xidmap file.
+// Outside upsert mode the nodes of the committed batches are linked to
+// through their UIDs, so the interrupted load must have kept them in xidMap.
+func LoadCheckpoint(source, checksum string, opts LoadOptions, xidMap string) (*Checkpoint, error) {
+	path := CheckpointPath(source)
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	c := NewCheckpoint(source, checksum, opts, "")
+	if err := json.Unmarshal(data, c); err != nil {
+		return nil, fmt.Errorf("parse %s: %w", path, err)
+	}
+	switch {
+	case c.Checksum != checksum:
+		return nil, fmt.Errorf("%s was written for a different dataset, sha256 %s instead of %s", path, c.Checksum, checksum)
+	case c.BatchSize != opts.BatchSize:
+		return nil, fmt.Errorf("%s was written with -batch %d", path, c.BatchSize)
+	case c.Upsert != opts.Upsert:
+		return nil, fmt.Errorf("%s was written with -upsert=%t", path, c.Upsert)
+	case !c.Upsert && c.XIDMap == "":
+		return nil, fmt.Errorf("%s was written by a load without -xidmap, the UIDs of its nodes are lost", path)
+	case c.XIDMap != xidMap:
+		return nil, fmt.Errorf("%s was written with -xidmap %s", path, c.XIDMap)
+	}
+
+	for _, index := range c.Committed {
+		c.done[index] = true
+	}
+	return c, nil
+}
+
+// Done reports whether batch index was committed.
+func (c *Checkpoint) Done(index int64) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return index < c.Next || c.done[index]
+}
+
+// Mark records batch index as committed and saves the checkpoint.
+func (c *Checkpoint) Mark(index int64) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.done[index] = true
+	for c.done[c.Next] {
+		delete(c.done, c.Next)
+		c.Next++
+	}
+
+	c.Committed = c.Committed[:0]
+	for index := range c.done {
+		c.Committed = append(c.Committed, index)
+	}
+	sort.Slice(c.Committed, func(i, j int) bool { return c.Committed[i] < c.Committed[j] })
+
+	data, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := c.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, c.path)
+}
+
+// Remove deletes the checkpoint file once the load is complete.
+func (c *Checkpoint) Remove() error {
+	err := os.Remove(c.path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	return err
+}
+
+// FileChecksum returns the hex encoded SHA-256 of the file at path.
+func FileChecksum(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
@@ -0,0 +1,662 @@
+package main
+
+import (
+	"bufio"
+	"compress/gzip"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Command is a single subcommand of the populator.
+type Command struct {
+	Name    string
+	Summary string
+	Run     func(args []string) error
+}
+
+func commands() []Command {
+	return []Command{
+		{Name: "generate", Summary: "generate a dataset file", Run: runGenerate},
+		{Name: "load", Summary: "send a generated or existing dataset to Dgraph over HTTP", Run: runLoad},
+		{Name: "schema", Summary: "print, write or apply the DQL schema of the dataset", Run: runSchema},
+		{Name: "validate", Summary: "check that a dataset file is well formed", Run: runValidate},
+		{Name: "stats", Summary: "print node and predicate counts of a dataset file", Run: runStats},
+		{Name: "verify", Summary: "compare the data loaded in Dgraph with a generation manifest", Run: runVerify},
+		{Name: "reset", Summary: "drop data, or data and schema, from Dgraph", Run: runReset},
+	}
+}
+
+// Run dispatches args to the matching subcommand.
+func Run(args []string) error {
+	if len(args) == 0 || args[0] == "-h" || args[0] == "--help" || args[0] == "help" {
+		usage(os.Stderr)
+		return nil
+	}
+
+	for _, cmd := range commands() {
+		if cmd.Name == args[0] {
+			if err := cmd.Run(args[1:]); !errors.Is(err, flag.ErrHelp) {
+				return err
+			}
+			return nil
+		}
+	}
+
+	usage(os.Stderr)
+	return fmt.Errorf("unknown command %q", args[0])
+}
+
+func usage(w io.Writer) {
+	fmt.Fprintf(w, "Usage: dgraph-populator <command> [flags]\n\nCommands:\n")
+	for _, cmd := range commands() {
+		fmt.Fprintf(w, "  %-10s %s\n", cmd.Name, cmd.Summary)
+	}
+	fmt.Fprintf(w, "\nRun 'dgraph-populator <command> -h' for the flags of a command.\n")
+}
+
+// generatorFlags are the flags of every command that generates a dataset.
+type generatorFlags struct {
+	fs            *flag.FlagSet
+	config        *string
+	numOfCustomer *int
+	numOfProduct  *int
+	seed          *int64
+	xids          *string
+	facets        *string
+	nodePrefix    *string
+}
+
+func addGeneratorFlags(fs *flag.FlagSet) *generatorFlags {
+	return &generatorFlags{
+		fs:            fs,
+		config:        fs.String("config", "", "path of a JSON scenario file"),
+		numOfCustomer: fs.Int("customers", 10000, "number of customers to generate, overrides the scenario"),
+		numOfProduct:  fs.Int("products", 1000, "number of products to generate, overrides the scenario"),
+		seed:          fs.Int64("seed", 0, "seed of every random value, overrides the scenario; 0 picks one from the clock"),
+		xids:          fs.String("xids", XIDsRandom, "XID mode, overrides the scenario: random, or stable for name-based city and category XIDs and time-based invoice XIDs"),
+		facets:        fs.String("facets", "", "comma separated edges given facets, overrides the scenario: destination, order, order_product"),
+		nodePrefix:    fs.String("node-prefix", "", "prefix of every blank node label, e.g. run2 for _:run2C1"),
+	}
+}
+
+// Scenario resolves the scenario file and the flags overriding it. It must be
+// called after the flag set is parsed.
+func (g *generatorFlags) Scenario() (Scenario, error) {
+	scenario := DefaultScenario()
+	if *g.config != "" {
+		var err error
+		if scenario, err = LoadScenario(*g.config); err != nil {
+			return scenario, err
+		}
+	}
+	g.fs.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "customers":
+			scenario.Customers = *g.numOfCustomer
+		case "products":
+			scenario.Products = *g.numOfProduct
+		case "seed":
+			scenario.Seed = *g.seed
+		case "xids":
+			scenario.XIDs = *g.xids
+		case "facets":
+			scenario.Facets = nil
+			for _, predicate := range strings.Split(*g.facets, ",") {
+				if predicate = strings.TrimSpace(predicate); predicate != "" {
+					scenario.Facets = append(scenario.Facets, predicate)
+				}
+			}
+		}
+	})
+	if scenario.Seed == 0 {
+		scenario.Seed = time.Now().UnixNano()
+	}
+	if err := scenario.Validate(); err != nil {
+		return scenario, fmt.Errorf("invalid scenario: %w", err)
+	}
+
+	if *g.nodePrefix != "" && !IsBlankLabel(*g.nodePrefix) {
+		return scenario, fmt.Errorf("invalid node prefix %q", *g.nodePrefix)
+	}
+	return scenario, nil
+}
+
+// Generate generates the scenario into w, then closes w.
+func (g *generatorFlags) Generate(w DatasetWriter, scenario Scenario) error {
+	var dst DatasetWriter = w
+	if *g.nodePrefix != "" {
+		dst = PrefixWriter{DatasetWriter: w, Prefix: *g.nodePrefix}
+	}
+	if err := Generate(dst, scenario); err != nil {
+		w.Close()
+		return err
+	}
+	return w.Close()
+}
+
+// clientFlags are the flags of every command talking to Dgraph, over plain
+// HTTP or TLS depending on the scheme of the host. The
+// credentials default to the DGRAPH_USER, DGRAPH_PASSWORD, DGRAPH_NAMESPACE
+// and DGRAPH_API_KEY environment variables, keeping them out of the process
+// list. The password and API key are read from the environment only once the
+// flags are parsed, so the usage text never prints them as defaults.
+type clientFlags struct {
+	host         *string
+	user         *string
+	password     *string
+	namespace    *uint64
+	apiKey       *string
+	apiKeyHeader *string
+	tls          TLSOptions
+}
+
+func addClientFlags(fs *flag.FlagSet) *clientFlags {
+	namespace, _ := strconv.ParseUint(os.Getenv("DGRAPH_NAMESPACE"), 10, 64)
+	c := &clientFlags{
+		host:         fs.String("host", DgraphHost, "Dgraph Alpha HTTP endpoint"),
+		user:         fs.String("user", os.Getenv("DGRAPH_USER"), "ACL user to log in as, $DGRAPH_USER"),
+		password:     fs.String("password", "", "ACL password, defaults to $DGRAPH_PASSWORD"),
+		namespace:    fs.Uint64("namespace", namespace, "ACL namespace to log in to, $DGRAPH_NAMESPACE"),
+		apiKey:       fs.String("api-key", "", "API key sent with every request, defaults to $DGRAPH_API_KEY"),
+		apiKeyHeader: fs.String("api-key-header", "X-Auth-Token", "header carrying -api-key, e.g. Dg-Auth"),
+	}
+	fs.StringVar(&c.tls.CACert, "tls-ca", "", "PEM bundle of the CAs signing the Alpha certificate, instead of the system ones")
+	fs.StringVar(&c.tls.ClientCert, "tls-cert", "", "PEM client certificate for mutual TLS")
+	fs.StringVar(&c.tls.ClientKey, "tls-key", "", "PEM key of -tls-cert")
+	fs.StringVar(&c.tls.ServerName, "tls-server-name", "", "name to verify the Alpha certificate against instead of the -host name")
+	return c
+}
+
+// Client returns a client for the flags, logged in when a user is given. It
+// must be called after the flag set is parsed.
+func (c *clientFlags) Client() (*Client, error) {
+	if *c.password == "" && *c.user != "" {
+		*c.password = os.Getenv("DGRAPH_PASSWORD")
+	}
+	if *c.apiKey == "" {
+		*c.apiKey = os.Getenv("DGRAPH_API_KEY")
+	}
+
+	client := NewClient(*c.host)
+	client.APIKey = *c.apiKey
+	client.APIKeyHeader = *c.apiKeyHeader
+	if c.tls.Enabled() {
+		if !strings.HasPrefix(client.Host, "https://") {
+			return nil, errors.New("the -tls flags need an https:// -host")
+		}
+		if err := client.SetTLS(c.tls); err != nil {
+			return nil, fmt.Errorf("TLS: %w", err)
+		}
+	}
+
+	if *c.user == "" {
+		if *c.password != "" || *c.namespace != 0 {
+			return nil, errors.New("-password and -namespace need a -user")
+		}
+		return client, nil
+	}
+	if err := client.Login(*c.user, *c.password, *c.namespace); err != nil {
+		return nil, fmt.Errorf("log in to %s as %s: %w", *c.host, *c.user, err)
+	}
+	return client, nil
+}
+
+func runGenerate(args []string) error {
+	fs := flag.NewFlagSet("generate", flag.ContinueOnError)
+	generator := addGeneratorFlags(fs)
+	output := fs.String("output", OutputFile, "path of the generated dataset, gzip compressed when it ends in .gz")
+	format := fs.String("format", FormatRDF, "output format: rdf for N-Quads, or json for Dgraph JSON mutations")
+	gzipLevel := fs.Int("gzip-level", gzip.DefaultCompression, "gzip compression level, 1 (fastest) to 9 (best)")
+	shards := fs.Int("shards", 1, "split the dataset into this many files, e.g. dataset.0.rdf.gz, next to a dataset.schema")
+	shardBy := fs.String("shard-by", ShardBySubject, "how quads are spread over the shards: subject keeps every quad of a node together, round-robin deals them in turn")
+	perEntity := fs.Bool("per-entity", false, "write cities, categories, customers, products and invoices to their own files next to -output, e.g. cities.rdf")
+	force := fs.Bool("force", false, "overwrite existing output files")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *shards < 1 {
+		return errors.New("shards must be at least 1")
+	}
+	if *perEntity && *shards > 1 {
+		return errors.New("-per-entity cannot be combined with -shards")
+	}
+
+	scenario, err := generator.Scenario()
+	if err != nil {
+		return err
+	}
+
+	paths := []string{*output}
+	var phases []string // phase of every path, for per-entity files
+	entityPaths := EntityPaths(*output)
+	switch {
+	case *perEntity:
+		paths, phases = nil, Phases
+		for _, phase := range Phases {
+			paths = append(paths, entityPaths[phase])
+		}
+	case *shards > 1:
+		paths = ShardPaths(*output, *shards)
+	}
+	withSchema := *shards > 1 || *perEntity
+	sidecars := []string{ManifestPath(*output)}
+	if withSchema {
+		sidecars = append(sidecars, SchemaPath(*output))
+	}
+	if err := CheckOverwrite(append(sidecars, paths...), *force); err != nil {
+		return err
+	}
+
+	// The dataset files, schema and manifest are written under temporary
+	// names and only renamed into place together once complete.
+	stop := AbortOutputsOnInterrupt()
+	defer stop()
+	defer AbortOutputs()
+
+	var w DatasetWriter
+	switch {
+	case *perEntity:
+		w, err = OpenEntityDataset(entityPaths, *format, *gzipLevel)
+	case *shards > 1:
+		w, err = OpenShardedDataset(paths, *format, *gzipLevel, *shardBy)
+	default:
+		w, err = OpenDatasetFile(*output, *format, *gzipLevel)
+	}
+	if err != nil {
+		return err
+	}
+	manifest := NewManifest(scenario)
+	if err := generator.Generate(ManifestWriter{DatasetWriter: w, Manifest: manifest}, scenario); err != nil {
+		return err
+	}
+
+	for i, path := range paths {
+		var phase string
+		if phases != nil {
+			phase = phases[i]
+		}
+		if err := manifest.AddFile(path, phase); err != nil {
+			return err
+		}
+	}
+	if withSchema {
+		if err := WriteOutput(SchemaPath(*output), []byte(GenerateSchema())); err != nil {
+			return err
+		}
+	}
+	if err := manifest.Write(ManifestPath(*output)); err != nil {
+		return err
+	}
+	return CommitOutputs()
+}
+
+func runLoad(args []string) error {
+	fs := flag.NewFlagSet("load", flag.ContinueOnError)
+	generator := addGeneratorFlags(fs)
+	input := fs.String("input", "", "load this dataset file instead of generating one")
+	dgraph := addClientFlags(fs)
+	xidMap := fs.String("xidmap", "", "file mapping blank nodes to UIDs, reused to link to nodes of earlier loads")
+	resume := fs.Bool("resume", false, "skip the batches of -input committed by an interrupted load, as recorded in its checkpoint file")
+	opts := DefaultLoadOptions()
+	fs.IntVar(&opts.BatchSize, "batch", opts.BatchSize, "number of quads per mutation")
+	fs.IntVar(&opts.Workers, "workers", opts.Workers, "number of mutations sent in parallel")
+	fs.IntVar(&opts.Queue, "queue", opts.Queue, "number of batches queued for the workers before generation waits")
+	fs.IntVar(&opts.Retries, "retries", opts.Retries, "retries of a mutation failing with an aborted transaction or a 5xx")
+	fs.DurationVar(&opts.Backoff, "backoff", opts.Backoff, "delay before the first retry, doubled on every retry")
+	fs.BoolVar(&opts.Upsert, "upsert", false, "update nodes matching on xid, or on name for cities and categories, instead of creating them")
+	fs.StringVar(&opts.Format, "format", opts.Format, "mutation format: rdf, or json for Dgraph JSON mutations")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if opts.Upsert && *xidMap != "" {
+		return errors.New("-upsert matches nodes on xid and cannot be combined with -xidmap")
+	}
+	if opts.Format != FormatRDF && opts.Format != FormatJSON {
+		return fmt.Errorf("unsupported format %q", opts.Format)
+	}
+	if opts.Upsert && opts.Format == FormatJSON {
+		return errors.New("-upsert sends RDF upsert blocks and cannot be combined with -format json")
+	}
+	if *resume && *input == "" {
+		return errors.New("-resume needs an -input file")
+	}
+	if *resume && !opts.Upsert && *xidMap == "" {
+		return errors.New("-resume needs -xidmap or -upsert to link to the nodes of skipped batches")
+	}
+	if opts.BatchSize < 1 || opts.Workers < 1 {
+		return errors.New("batch and workers must be at least 1")
+	}
+	if opts.Queue < 0 || opts.Retries < 0 {
+		return errors.New("queue and retries must not be negative")
+	}
+
+	uids := NewUIDMap()
+	if *xidMap != "" {
+		var err error
+		if uids, err = OpenUIDMap(*xidMap); err != nil {
+			return err
+		}
+		defer uids.Close()
+		log.Printf("Loaded %d UIDs from %s", uids.Len(), *xidMap)
+	}
+
+	var progress *Checkpoint
+	if *input != "" {
+		var err error
+		if progress, err = openCheckpoint(*input, opts, *xidMap, *resume); err != nil {
+			return err
+		}
+	}
+
+	client, err := dgraph.Client()
+	if err != nil {
+		return err
+	}
+	loader := NewLoader(client, opts, uids)
+	loader.Checkpoint = progress
+	checkpoint := time.Now()
+	if *input != "" {
+		if err := LoadFile(loader, *input); err != nil {
+			return err
+		}
+		if err := progress.Remove(); err != nil {
+			return err
+		}
+	} else {
+		scenario, err := generator.Scenario()
+		if err != nil {
+			return err
+		}
+		if err := generator.Generate(loader, scenario); err != nil {
+			return err
+		}
+	}
+
+	if loader.Skipped() > 0 {
+		log.Printf("Skipped %d quads committed before", loader.Skipped())
+	}
+	log.Printf("Loaded %d quads in %d mutations to %s in %s", loader.Quads(), loader.Batches(), *dgraph.host, time.Since(checkpoint))
+	return nil
+}
+
+// openCheckpoint returns the checkpoint of a load from the file input with the
+// xidmap file xidMap, picking up the one of an interrupted load when resume
+// is set.
+func openCheckpoint(input string, opts LoadOptions, xidMap string, resume bool) (*Checkpoint, error) {
+	checksum, err := FileChecksum(input)
+	if err != nil {
+		return nil, err
+	}
+	if xidMap != "" {
+		if xidMap, err = filepath.Abs(xidMap); err != nil {
+			return nil, err
+		}
+	}
+
+	if resume {
+		c, err := LoadCheckpoint(input, checksum, opts, xidMap)
+		if err != nil {
+			return nil, err
+		}
+		log.Printf("Resuming %s after batch %d", input, c.Next)
+		return c, nil
+	}
+
+	if _, err := os.Stat(CheckpointPath(input)); err == nil {
+		return nil, fmt.Errorf("%s exists, pass -resume to continue the interrupted load or remove it", CheckpointPath(input))
+	}
+	return NewCheckpoint(input, checksum, opts, xidMap), nil
+}
+
+func runSchema(args []string) error {
+	fs := flag.NewFlagSet("schema", flag.ContinueOnError)
+	output := fs.String("output", "", "write the schema to this file instead of stdout")
+	push := fs.Bool("push", false, "apply the schema through /alter of -host")
+	dgraph := addClientFlags(fs)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	schema := GenerateSchema()
+	if *push {
+		client, err := dgraph.Client()
+		if err != nil {
+			return err
+		}
+		if err := client.Alter(schema); err != nil {
+			return err
+		}
+		log.Printf("Schema applied to %s", *dgraph.host)
+	}
+
+	switch {
+	case *output != "":
+		return os.WriteFile(*output, []byte(schema), 0644)
+	case !*push:
+		_, err := io.WriteString(os.Stdout, schema)
+		return err
+	}
+	return nil
+}
+
+func runValidate(args []string) error {
+	fs := flag.NewFlagSet("validate", flag.ContinueOnError)
+	input := fs.String("input", OutputFile, "path of the dataset to validate")
+	selfCheck := fs.Int("selfcheck", 0, "round trip this many random literals through the encoder instead of reading a dataset")
+	selfCheckSeed := fs.Int64("seed", 0, "seed of the -selfcheck literals, 0 picks one from the clock")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if *selfCheck > 0 {
+		seed := *selfCheckSeed
+		if seed == 0 {
+			seed = time.Now().UnixNano()
+		}
+		if err := SelfCheckLiterals(*selfCheck, seed); err != nil {
+			return fmt.Errorf("self-check with -seed %d: %w", seed, err)
+		}
+		fmt.Printf("%d literals round tripped\n", *selfCheck)
+		return nil
+	}
+
+	var lines, invalid int
+	err := scanQuads(*input, func(lineNo int, line string) error {
+		lines++
+		if _, err := ParseQuad(line); err != nil {
+			invalid++
+			fmt.Fprintf(os.Stderr, "%s:%d: %s\n", *input, lineNo, err)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("%d lines, %d invalid\n", lines, invalid)
+	if invalid > 0 {
+		return fmt.Errorf("%s has %d invalid lines", *input, invalid)
+	}
+	return nil
+}
+
+func runStats(args []string) error {
+	fs := flag.NewFlagSet("stats", flag.ContinueOnError)
+	input := fs.String("input", OutputFile, "path of the dataset to inspect")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	var quads, edges int
+	subjects := make(map[string]struct{})
+	entities := make(map[string]int)
+	predicates := make(map[string]int)
+	err := scanQuads(*input, func(lineNo int, line string) error {
+		quad, err := ParseQuad(line)
+		if err != nil {
+			return fmt.Errorf("%s:%d: %w", *input, lineNo, err)
+		}
+
+		quads++
+		if quad.IsEdge() {
+			edges++
+		}
+		subjects[quad.Subject] = struct{}{}
+		predicates[quad.Predicate]++
+		if quad.Predicate == Entity {
+			entities[fmt.Sprint(quad.Value)]++
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("quads:    %d\n", quads)
+	fmt.Printf("edges:    %d\n", edges)
+	fmt.Printf("nodes:    %d\n", len(subjects))
+	fmt.Printf("\nentities:\n")
+	printCounts(entities)
+	fmt.Printf("\npredicates:\n")
+	printCounts(predicates)
+	return nil
+}
+
+func runVerify(args []string) error {
+	fs := flag.NewFlagSet("verify", flag.ContinueOnError)
+	path := fs.String("manifest", ManifestPath(OutputFile), "path of the manifest written by generate")
+	dgraph := addClientFlags(fs)
+	files := fs.Bool("files", false, "also check the checksums of the dataset files listed in the manifest")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	manifest, err := ReadManifest(*path)
+	if err != nil {
+		return err
+	}
+	if *files {
+		if err := VerifyFiles(manifest); err != nil {
+			return err
+		}
+	}
+
+	client, err := dgraph.Client()
+	if err != nil {
+		return err
+	}
+	checks, mismatches, err := VerifyCounts(client, manifest)
+	if err != nil {
+		return err
+	}
+	for _, mismatch := range mismatches {
+		fmt.Println(mismatch)
+	}
+	fmt.Printf("%d checks, %d mismatches\n", checks, len(mismatches))
+	if len(mismatches) > 0 {
+		return fmt.Errorf("%s does not match the data of %s", *dgraph.host, *path)
+	}
+	return nil
+}
+
+func runReset(args []string) error {
+	fs := flag.NewFlagSet("reset", flag.ContinueOnError)
+	mode := fs.String("drop", "", "what to drop: all (data and schema), data (keep the schema), or populator (the predicates and types of the generated entities)")
+	dgraph := addClientFlags(fs)
+	yes := fs.Bool("yes", false, "do not ask for confirmation")
+	dryRun := fs.Bool("dry-run", false, "print the drop operations without applying them")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *mode == "" {
+		return errors.New("-drop is required")
+	}
+
+	ops, err := ResetOps(*mode)
+	if err != nil {
+		return err
+	}
+	if *dryRun {
+		for _, op := range ops {
+			fmt.Println(op)
+		}
+		return nil
+	}
+
+	if !*yes {
+		prompt := fmt.Sprintf("This will %s on %s.", ops[0], *dgraph.host)
+		if len(ops) > 1 {
+			prompt = fmt.Sprintf("This will drop %d predicates and types on %s.", len(ops), *dgraph.host)
+		}
+		if !Confirm(os.Stdin, os.Stderr, prompt, *dgraph.host) {
+			return errors.New("reset aborted")
+		}
+	}
+
+	client, err := dgraph.Client()
+	if err != nil {
+		return err
+	}
+	for _, op := range ops {
+		if err := client.Drop(op); err != nil {
+			return fmt.Errorf("%s: %w", op, err)
+		}
+	}
+	log.Printf("Reset %s: %d drop operations applied", *dgraph.host, len(ops))
+	return nil
+}
+
+func printCounts(counts map[string]int) {
+	keys := make([]string, 0, len(counts))
+	for key := range counts {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		fmt.Printf("  %-24s %d\n", key, counts[key])
+	}
+}
+
+// scanQuads calls fn for every non-empty, non-comment line of the file at path.
+func scanQuads(path string, fn func(lineNo int, line string) error) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	var r io.Reader = f
+	if IsGzipPath(path) {
+		gz, err := gzip.NewReader(f)
+		if err != nil {
+			return err
+		}
+		defer gz.Close()
+		r = gz
+	}
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	lineNo := 0
+	for scanner.Scan() {
+		lineNo++
+		line := scanner.Text()
+		if line == "" || line[0] == '#' {
+			continue
+		}
+		if err := fn(lineNo, line); err != nil {
+			return err
+		}
+	}
+	return scanner.Err()
+}
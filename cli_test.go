@@ -0,0 +1,98 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestClientFlagsKeepSecretsOutOfUsage(t *testing.T) {
+	t.Setenv("DGRAPH_PASSWORD", "hunter2")
+	t.Setenv("DGRAPH_API_KEY", "s3cr3t-key")
+
+	var usage bytes.Buffer
+	fs := flag.NewFlagSet("load", flag.ContinueOnError)
+	fs.SetOutput(&usage)
+	dgraph := addClientFlags(fs)
+	if err := fs.Parse([]string{"-bogus"}); err == nil {
+		t.Fatal("parsed an unknown flag")
+	}
+	if strings.Contains(usage.String(), "hunter2") || strings.Contains(usage.String(), "s3cr3t-key") {
+		t.Errorf("usage prints the credentials of the environment:\n%s", usage.String())
+	}
+
+	var apiKey string
+	alpha := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		apiKey = r.Header.Get("X-Auth-Token")
+		w.Write([]byte(`{"data":{"code":"Success"}}`))
+	}))
+	defer alpha.Close()
+
+	fs = flag.NewFlagSet("load", flag.ContinueOnError)
+	dgraph = addClientFlags(fs)
+	if err := fs.Parse([]string{"-host", alpha.URL}); err != nil {
+		t.Fatal(err)
+	}
+	client, err := dgraph.Client()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := client.Alter(""); err != nil {
+		t.Fatal(err)
+	}
+	if apiKey != "s3cr3t-key" {
+		t.Errorf("sent API key %q, want the one of $DGRAPH_API_KEY", apiKey)
+	}
+}
+
+func TestGenerateRefusesToOverwriteSidecars(t *testing.T) {
+	dir := t.TempDir()
+	output := filepath.Join(dir, "s.rdf")
+	generate := func(args ...string) error {
+		return runGenerate(append([]string{"-customers", "5", "-products", "5", "-seed", "1", "-output", output}, args...))
+	}
+
+	if err := generate("-shards", "2"); err != nil {
+		t.Fatal(err)
+	}
+	manifest, err := os.ReadFile(ManifestPath(output))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The single file is new, but the manifest of the sharded run is not.
+	if err := generate(); err == nil || !strings.Contains(err.Error(), ManifestPath(output)) {
+		t.Fatalf("generate error = %v, want the manifest to exist", err)
+	}
+	if after, _ := os.ReadFile(ManifestPath(output)); !bytes.Equal(after, manifest) {
+		t.Error("manifest of the sharded run was overwritten")
+	}
+	if _, err := os.Stat(output); !os.IsNotExist(err) {
+		t.Errorf("refused run left %s behind: %v", output, err)
+	}
+
+	if err := generate("-force"); err != nil {
+		t.Fatal(err)
+	}
+	m, err := ReadManifest(ManifestPath(output))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(m.Files) != 1 || m.Files[0].Path != output {
+		t.Fatalf("manifest lists %+v, want %s only", m.Files, output)
+	}
+	if checksum, _ := FileChecksum(output); m.Files[0].SHA256 != checksum {
+		t.Errorf("manifest checksum %s, file has %s", m.Files[0].SHA256, checksum)
+	}
+	entries, _ := os.ReadDir(dir)
+	for _, entry := range entries {
+		if strings.HasSuffix(entry.Name(), ".tmp") {
+			t.Errorf("temporary file %s left behind", entry.Name())
+		}
+	}
+}
@@ -0,0 +1,417 @@
+package main
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptrace"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Client talks to the HTTP API of a Dgraph Alpha.
+//
+// APIKey is sent in the APIKeyHeader of every request, as hosted Dgraph
+// expects. On an Alpha with ACL enabled, Login obtains the access JWT sent
+// with every request; once it expires it is renewed with the refresh JWT, or
+// by logging in again.
+type Client struct {
+	Host         string
+	HTTP         *http.Client
+	APIKey       string
+	APIKeyHeader string
+
+	mu         sync.Mutex
+	login      *loginRequest // credentials of the last Login
+	accessJWT  string
+	refreshJWT string
+}
+
+type loginRequest struct {
+	UserID       string `json:"userid,omitempty"`
+	Password     string `json:"password,omitempty"`
+	Namespace    uint64 `json:"namespace,omitempty"`
+	RefreshToken string `json:"refresh_token,omitempty"`
+}
+
+// NewClient returns a client for the Alpha at host, e.g. DgraphHost.
+func NewClient(host string) *Client {
+	return &Client{
+		Host:         strings.TrimRight(host, "/"),
+		HTTP:         &http.Client{Timeout: 5 * time.Minute},
+		APIKeyHeader: "X-Auth-Token",
+	}
+}
+
+// TLSOptions configure the TLS connection to an Alpha.
+type TLSOptions struct {
+	CACert     string // PEM bundle of the CAs to trust instead of the system pool
+	ClientCert string // PEM client certificate for mutual TLS
+	ClientKey  string // PEM key of ClientCert
+	ServerName string // name to verify the server certificate against instead of the host
+}
+
+// Enabled reports whether any option is set.
+func (o TLSOptions) Enabled() bool {
+	return o != TLSOptions{}
+}
+
+// Config returns the tls.Config of the options.
+func (o TLSOptions) Config() (*tls.Config, error) {
+	config := &tls.Config{ServerName: o.ServerName}
+
+	if o.CACert != "" {
+		pem, err := os.ReadFile(o.CACert)
+		if err != nil {
+			return nil, err
+		}
+		config.RootCAs = x509.NewCertPool()
+		if !config.RootCAs.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates in %s", o.CACert)
+		}
+	}
+
+	if (o.ClientCert == "") != (o.ClientKey == "") {
+		return nil, errors.New("a client certificate needs its key and the other way round")
+	}
+	if o.ClientCert != "" {
+		cert, err := tls.LoadX509KeyPair(o.ClientCert, o.ClientKey)
+		if err != nil {
+			return nil, err
+		}
+		config.Certificates = []tls.Certificate{cert}
+	}
+	return config, nil
+}
+
+// SetTLS makes the client connect with opts.
+func (c *Client) SetTLS(opts TLSOptions) error {
+	config, err := opts.Config()
+	if err != nil {
+		return err
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = config
+	c.HTTP.Transport = transport
+	return nil
+}
+
+// Login logs in as user to namespace through /login.
+func (c *Client) Login(user, password string, namespace uint64) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.authenticate(&loginRequest{UserID: user, Password: password, Namespace: namespace})
+}
+
+// renew replaces the access JWT stale, unless another request renewed it
+// already. It uses the refresh JWT, falling back to the credentials of Login
+// once the refresh JWT has expired too.
+func (c *Client) renew(stale string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.accessJWT != stale {
+		return nil
+	}
+	if err := c.authenticate(&loginRequest{RefreshToken: c.refreshJWT}); err == nil || !isExpiredToken(err) {
+		return err
+	}
+	return c.authenticate(c.login)
+}
+
+// authenticate sends req to /login and keeps the JWTs it returns. c.mu must
+// be held.
+func (c *Client) authenticate(req *loginRequest) error {
+	body, err := json.Marshal(req)
+	if err != nil {
+		return err
+	}
+	var result struct {
+		Data struct {
+			AccessJWT  string `json:"accessJWT"`
+			RefreshJWT string `json:"refreshJWT"`
+		} `json:"data"`
+	}
+	if err := c.send("/login", "application/json", string(body), "", &result); err != nil {
+		return err
+	}
+	if result.Data.AccessJWT == "" {
+		return errors.New("/login: no access JWT in response")
+	}
+
+	if req.RefreshToken == "" {
+		c.login = req
+	}
+	c.accessJWT = result.Data.AccessJWT
+	c.refreshJWT = result.Data.RefreshJWT
+	return nil
+}
+
+func (c *Client) token() string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.accessJWT
+}
+
+// isExpiredToken reports whether err rejects an expired access or refresh JWT.
+func isExpiredToken(err error) bool {
+	var dgraphErr DgraphError
+	return errors.As(err, &dgraphErr) && strings.Contains(strings.ToLower(dgraphErr.Message), "token is expired")
+}
+
+// DgraphError is an error reported in the errors list of a Dgraph response.
+type DgraphError struct {
+	Message    string `json:"message"`
+	Extensions struct {
+		Code string `json:"code"`
+	} `json:"extensions"`
+}
+
+func (e DgraphError) Error() string {
+	if e.Extensions.Code == "" {
+		return e.Message
+	}
+	return e.Extensions.Code + ": " + e.Message
+}
+
+// HTTPError is returned for responses with a non-2xx status.
+type HTTPError struct {
+	Path       string
+	StatusCode int
+	Body       string
+}
+
+func (e *HTTPError) Error() string {
+	return fmt.Sprintf("%s: %d %s: %s", e.Path, e.StatusCode, http.StatusText(e.StatusCode), e.Body)
+}
+
+// UnconfirmedError is returned when a request was sent in full but no
+// response came back, e.g. on a timeout. Dgraph may have applied it.
+type UnconfirmedError struct {
+	Path string
+	Err  error
+}
+
+func (e *UnconfirmedError) Error() string {
+	return e.Path + ": no response to the request sent: " + e.Err.Error()
+}
+
+func (e *UnconfirmedError) Unwrap() error {
+	return e.Err
+}
+
+// IsRetryable reports whether a request failing with err may succeed when
+// sent again: aborted transactions, 5xx responses and network errors other
+// than a rejected server certificate. An UnconfirmedError is not retryable,
+// since sending a mutation with blank nodes twice would create its nodes
+// twice.
+func IsRetryable(err error) bool {
+	var unconfirmed *UnconfirmedError
+	if errors.As(err, &unconfirmed) {
+		return false
+	}
+	var httpErr *HTTPError
+	if errors.As(err, &httpErr) {
+		return httpErr.StatusCode >= 500
+	}
+	var dgraphErr DgraphError
+	if errors.As(err, &dgraphErr) {
+		return strings.Contains(strings.ToLower(dgraphErr.Message), "aborted")
+	}
+	if isCertificateError(err) {
+		return false
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr)
+}
+
+// isCertificateError reports whether err rejects the certificate of the
+// server, which no retry can fix.
+func isCertificateError(err error) bool {
+	var authorityErr x509.UnknownAuthorityError
+	var hostnameErr x509.HostnameError
+	var invalidErr x509.CertificateInvalidError
+	return errors.As(err, &authorityErr) || errors.As(err, &hostnameErr) || errors.As(err, &invalidErr)
+}
+
+// Alter applies a DQL schema through /alter.
+func (c *Client) Alter(schema string) error {
+	return c.alter("application/dql", schema)
+}
+
+// DropOp is a drop operation of /alter.
+type DropOp struct {
+	DropAll   bool   `json:"drop_all,omitempty"`
+	DropOp    string `json:"drop_op,omitempty"`    // DATA, ATTR or TYPE
+	DropValue string `json:"drop_value,omitempty"` // predicate or type dropped by ATTR and TYPE
+}
+
+// String describes the operation.
+func (op DropOp) String() string {
+	switch {
+	case op.DropAll:
+		return "drop all data and schema"
+	case op.DropOp == "DATA":
+		return "drop all data but keep the schema"
+	case op.DropOp == "TYPE":
+		return "drop type " + op.DropValue
+	case op.DropOp == "ATTR":
+		return "drop predicate " + op.DropValue
+	}
+	return "drop " + op.DropOp + " " + op.DropValue
+}
+
+// Drop applies a drop operation through /alter.
+func (c *Client) Drop(op DropOp) error {
+	body, err := json.Marshal(op)
+	if err != nil {
+		return err
+	}
+	return c.alter("application/json", string(body))
+}
+
+func (c *Client) alter(contentType, body string) error {
+	var result MutationResult
+	if err := c.post("/alter", contentType, body, &result); err != nil {
+		return err
+	}
+	if result.Data.Code != "Success" {
+		return fmt.Errorf("alter: unexpected response code %q: %s", result.Data.Code, result.Data.Message)
+	}
+	return nil
+}
+
+// Mutate commits a batch of RDF quads through /mutate?commitNow=true.
+func (c *Client) Mutate(rdf string) (result MutationResult, err error) {
+	body := "{\n  set {\n" + rdf + "  }\n}"
+	if err = c.post("/mutate?commitNow=true", "application/rdf", body, &result); err != nil {
+		return result, err
+	}
+	if result.Data.Code != "Success" {
+		return result, fmt.Errorf("mutate: unexpected response code %q: %s", result.Data.Code, result.Data.Message)
+	}
+	return result, nil
+}
+
+// MutateJSON commits the JSON array of node objects nodes through
+// /mutate?commitNow=true.
+func (c *Client) MutateJSON(nodes string) (result MutationResult, err error) {
+	body := `{"set": ` + nodes + `}`
+	if err = c.post("/mutate?commitNow=true", "application/json", body, &result); err != nil {
+		return result, err
+	}
+	if result.Data.Code != "Success" {
+		return result, fmt.Errorf("mutate: unexpected response code %q: %s", result.Data.Code, result.Data.Message)
+	}
+	return result, nil
+}
+
+// Upsert commits an upsert block, query followed by a set mutation in RDF,
+// through /mutate?commitNow=true.
+func (c *Client) Upsert(query, rdf string) (result MutationResult, err error) {
+	body := "upsert {\n  query {\n" + query + "  }\n  mutation {\n    set {\n" + rdf + "    }\n  }\n}"
+	if err = c.post("/mutate?commitNow=true", "application/rdf", body, &result); err != nil {
+		return result, err
+	}
+	if result.Data.Code != "Success" {
+		return result, fmt.Errorf("upsert: unexpected response code %q: %s", result.Data.Code, result.Data.Message)
+	}
+	return result, nil
+}
+
+// Query runs a DQL query through /query and decodes the data of the response
+// into out.
+func (c *Client) Query(query string, out interface{}) error {
+	var result struct {
+		Data json.RawMessage `json:"data"`
+	}
+	if err := c.post("/query", "application/dql", query, &result); err != nil {
+		return err
+	}
+	if err := json.Unmarshal(result.Data, out); err != nil {
+		return fmt.Errorf("/query: decode data: %w", err)
+	}
+	return nil
+}
+
+// post sends body to path and decodes the JSON response into out, renewing
+// the access JWT and sending body again if it has expired.
+func (c *Client) post(path, contentType, body string, out interface{}) error {
+	token := c.token()
+	err := c.send(path, contentType, body, token, out)
+	if token == "" || !isExpiredToken(err) {
+		return err
+	}
+	if err := c.renew(token); err != nil {
+		return fmt.Errorf("renew access JWT: %w", err)
+	}
+	return c.send(path, contentType, body, c.token(), out)
+}
+
+// send sends body to path with the access JWT token, if any, and decodes the
+// JSON response into out. Non-2xx statuses and responses carrying errors are
+// returned as errors, and failures after the whole request was written as an
+// UnconfirmedError.
+func (c *Client) send(path, contentType, body, token string, out interface{}) error {
+	req, err := http.NewRequest(http.MethodPost, c.Host+path, strings.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", contentType)
+	if c.APIKey != "" {
+		req.Header.Set(c.APIKeyHeader, c.APIKey)
+	}
+	if token != "" {
+		req.Header.Set("X-Dgraph-AccessToken", token)
+	}
+	var wrote int32
+	req = req.WithContext(httptrace.WithClientTrace(req.Context(), &httptrace.ClientTrace{
+		WroteRequest: func(info httptrace.WroteRequestInfo) {
+			if info.Err == nil {
+				atomic.StoreInt32(&wrote, 1)
+			}
+		},
+	}))
+	unconfirmed := func(err error) error {
+		if atomic.LoadInt32(&wrote) == 1 {
+			return &UnconfirmedError{Path: path, Err: err}
+		}
+		return err
+	}
+
+	resp, err := c.HTTP.Do(req)
+	if err != nil {
+		return unconfirmed(err)
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return unconfirmed(err)
+	}
+	if resp.StatusCode/100 != 2 {
+		return &HTTPError{Path: path, StatusCode: resp.StatusCode, Body: strings.TrimSpace(string(data))}
+	}
+
+	var envelope struct {
+		Errors []DgraphError `json:"errors"`
+	}
+	if err := json.Unmarshal(data, &envelope); err != nil {
+		return fmt.Errorf("%s: decode response: %w", path, err)
+	}
+	if len(envelope.Errors) > 0 {
+		return fmt.Errorf("%s: %w", path, envelope.Errors[0])
+	}
+
+	if err := json.Unmarshal(data, out); err != nil {
+		return fmt.Errorf("%s: decode response: %w", path, err)
+	}
+	return nil
+}
@@ -0,0 +1,362 @@
+package main
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// newTLSAlpha starts an Alpha answering every mutation with success over TLS
+// with config, and counts the connections made to it.
+func newTLSAlpha(t *testing.T, config *tls.Config) (server *httptest.Server, conns *int32) {
+	conns = new(int32)
+	server = httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"data":{"code":"Success","uids":{}}}`))
+	}))
+	server.TLS = config
+	server.Config.ConnState = func(conn net.Conn, state http.ConnState) {
+		if state == http.StateNew {
+			atomic.AddInt32(conns, 1)
+		}
+	}
+	server.StartTLS()
+	t.Cleanup(server.Close)
+	return server, conns
+}
+
+// writePEM writes blocks of the type to a file in dir and returns its path.
+func writePEM(t *testing.T, dir, name, typ string, blocks ...[]byte) string {
+	t.Helper()
+	var data []byte
+	for _, block := range blocks {
+		data = append(data, pem.EncodeToMemory(&pem.Block{Type: typ, Bytes: block})...)
+	}
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// serverCA writes the certificate of a TLS test server to dir, for -tls-ca.
+func serverCA(t *testing.T, server *httptest.Server, dir string) string {
+	return writePEM(t, dir, "ca.crt", "CERTIFICATE", server.Certificate().Raw)
+}
+
+// newClientCert returns a CA and a client certificate it signs, written to
+// dir as client.crt and client.key.
+func newClientCert(t *testing.T, dir string) (ca *x509.Certificate, certPath, keyPath string) {
+	t.Helper()
+	caKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	caTemplate := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "Dgraph Root CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageCertSign,
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+	}
+	caDER, err := x509.CreateCertificate(rand.Reader, caTemplate, caTemplate, &caKey.PublicKey, caKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ca, err = x509.ParseCertificate(caDER); err != nil {
+		t.Fatal(err)
+	}
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(2),
+		Subject:      pkix.Name{CommonName: "populator"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, ca, &key.PublicKey, caKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return ca, writePEM(t, dir, "client.crt", "CERTIFICATE", der), writePEM(t, dir, "client.key", "EC PRIVATE KEY", keyDER)
+}
+
+// tlsClient returns a client for host connecting with opts.
+func tlsClient(t *testing.T, host string, opts TLSOptions) *Client {
+	t.Helper()
+	client := NewClient(host)
+	if err := client.SetTLS(opts); err != nil {
+		t.Fatal(err)
+	}
+	return client
+}
+
+func TestTLSCustomCA(t *testing.T) {
+	server, _ := newTLSAlpha(t, nil)
+	ca := serverCA(t, server, t.TempDir())
+
+	if _, err := tlsClient(t, server.URL, TLSOptions{CACert: ca}).Mutate(""); err != nil {
+		t.Errorf("mutate with -tls-ca: %v", err)
+	}
+	_, err := tlsClient(t, server.URL, TLSOptions{}).Mutate("")
+	var authorityErr x509.UnknownAuthorityError
+	if !errors.As(err, &authorityErr) {
+		t.Errorf("mutate without -tls-ca: error = %v, want an unknown authority", err)
+	}
+}
+
+func TestTLSServerName(t *testing.T) {
+	server, _ := newTLSAlpha(t, nil)
+	ca := serverCA(t, server, t.TempDir())
+	// The test certificate is valid for example.com and 127.0.0.1, not for
+	// localhost.
+	host := strings.Replace(server.URL, "127.0.0.1", "localhost", 1)
+
+	_, err := tlsClient(t, host, TLSOptions{CACert: ca}).Mutate("")
+	var hostnameErr x509.HostnameError
+	if !errors.As(err, &hostnameErr) {
+		t.Errorf("mutate without -tls-server-name: error = %v, want a hostname mismatch", err)
+	}
+	if _, err := tlsClient(t, host, TLSOptions{CACert: ca, ServerName: "example.com"}).Mutate(""); err != nil {
+		t.Errorf("mutate with -tls-server-name: %v", err)
+	}
+}
+
+func TestTLSClientCertificate(t *testing.T) {
+	dir := t.TempDir()
+	clientCA, cert, key := newClientCert(t, dir)
+	clientCAs := x509.NewCertPool()
+	clientCAs.AddCert(clientCA)
+	server, _ := newTLSAlpha(t, &tls.Config{ClientAuth: tls.RequireAndVerifyClientCert, ClientCAs: clientCAs})
+	ca := serverCA(t, server, dir)
+
+	if _, err := tlsClient(t, server.URL, TLSOptions{CACert: ca, ClientCert: cert, ClientKey: key}).Mutate(""); err != nil {
+		t.Errorf("mutate with -tls-cert: %v", err)
+	}
+	if _, err := tlsClient(t, server.URL, TLSOptions{CACert: ca}).Mutate(""); err == nil {
+		t.Error("mutate without -tls-cert succeeded on an Alpha requiring client certificates")
+	}
+	if _, err := (TLSOptions{ClientCert: cert}).Config(); err == nil {
+		t.Error("accepted -tls-cert without -tls-key")
+	}
+}
+
+func TestTLSRejectedCertificateIsNotRetried(t *testing.T) {
+	server, conns := newTLSAlpha(t, nil)
+
+	opts := testLoadOptions()
+	opts.Workers = 1
+	_, err := load(t, tlsClient(t, server.URL, TLSOptions{}), opts, testDataset(1))
+	if !isCertificateError(err) {
+		t.Fatalf("load error = %v, want a certificate error", err)
+	}
+	if IsRetryable(err) {
+		t.Errorf("IsRetryable(%v) = true", err)
+	}
+	if n := atomic.LoadInt32(conns); n != 1 {
+		t.Errorf("connected %d times, want once", n)
+	}
+}
+
+// aclAlpha is an Alpha with ACL enabled. Only the last access JWT it issued
+// is valid, until expire is called; refresh JWTs stay valid until
+// expireRefresh is called.
+type aclAlpha struct {
+	*httptest.Server
+
+	mu             sync.Mutex
+	logins         []loginRequest
+	issued         int
+	access         string
+	refreshExpired bool
+	mutations      int
+}
+
+func newACLAlpha(t *testing.T) *aclAlpha {
+	alpha := &aclAlpha{}
+	alpha.Server = httptest.NewServer(http.HandlerFunc(alpha.serve))
+	t.Cleanup(alpha.Close)
+	return alpha
+}
+
+const expiredToken = `{"errors":[{"message":"unable to parse jwt token: Token is expired","extensions":{"code":"ErrorInvalidRequest"}}]}`
+
+func (a *aclAlpha) serve(w http.ResponseWriter, r *http.Request) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	switch r.URL.Path {
+	case "/login":
+		var req loginRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		a.logins = append(a.logins, req)
+		if req.RefreshToken != "" && a.refreshExpired {
+			w.Write([]byte(expiredToken))
+			return
+		}
+		if req.RefreshToken == "" && req.Password != "password" {
+			w.Write([]byte(`{"errors":[{"message":"invalid username or password"}]}`))
+			return
+		}
+		a.issued++
+		a.access = fmt.Sprintf("access-%d", a.issued)
+		a.refreshExpired = false
+		fmt.Fprintf(w, `{"data":{"accessJWT":%q,"refreshJWT":"refresh-%d"}}`, a.access, a.issued)
+	case "/mutate":
+		if token := r.Header.Get("X-Dgraph-AccessToken"); token == "" || token != a.access {
+			w.Write([]byte(expiredToken))
+			return
+		}
+		a.mutations++
+		w.Write([]byte(`{"data":{"code":"Success","uids":{}}}`))
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// expire invalidates the current access JWT, and the refresh JWT too if
+// refresh is set.
+func (a *aclAlpha) expire(refresh bool) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.access = ""
+	a.refreshExpired = refresh
+}
+
+func (a *aclAlpha) state() (logins []loginRequest, mutations int) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return append([]loginRequest(nil), a.logins...), a.mutations
+}
+
+// aclClient returns a client logged in to alpha as groot in namespace 2.
+func aclClient(t *testing.T, alpha *aclAlpha) *Client {
+	t.Helper()
+	client := NewClient(alpha.URL)
+	if err := client.Login("groot", "password", 2); err != nil {
+		t.Fatal(err)
+	}
+	return client
+}
+
+func TestLoginSendsNamespaceAndAccessToken(t *testing.T) {
+	alpha := newACLAlpha(t)
+	if err := NewClient(alpha.URL).Login("groot", "wrong", 2); err == nil {
+		t.Error("login with a wrong password succeeded")
+	}
+	client := aclClient(t, alpha)
+
+	logins, _ := alpha.state()
+	if want := (loginRequest{UserID: "groot", Password: "password", Namespace: 2}); logins[len(logins)-1] != want {
+		t.Errorf("login request = %+v, want %+v", logins[len(logins)-1], want)
+	}
+	if _, err := client.Mutate(""); err != nil {
+		t.Fatalf("mutate with the access JWT: %v", err)
+	}
+	if _, err := NewClient(alpha.URL).Mutate(""); err == nil {
+		t.Error("mutate without logging in succeeded")
+	}
+}
+
+func TestExpiredAccessTokenIsRefreshed(t *testing.T) {
+	alpha := newACLAlpha(t)
+	client := aclClient(t, alpha)
+	alpha.expire(false)
+
+	if _, err := client.Mutate(""); err != nil {
+		t.Fatalf("mutate with an expired access JWT: %v", err)
+	}
+	logins, mutations := alpha.state()
+	if len(logins) != 2 || logins[1] != (loginRequest{RefreshToken: "refresh-1"}) {
+		t.Errorf("logins = %+v, want one refresh with refresh-1", logins)
+	}
+	if mutations != 1 {
+		t.Errorf("applied %d mutations, want 1", mutations)
+	}
+}
+
+func TestConcurrentRequestsRefreshOnce(t *testing.T) {
+	alpha := newACLAlpha(t)
+	client := aclClient(t, alpha)
+	alpha.expire(false)
+
+	const callers = 8
+	var wg sync.WaitGroup
+	errs := make(chan error, callers)
+	for i := 0; i < callers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := client.Mutate("")
+			errs <- err
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Error(err)
+		}
+	}
+
+	logins, mutations := alpha.state()
+	if len(logins) != 2 {
+		t.Errorf("logged in %d times, want once and one refresh: %+v", len(logins), logins)
+	}
+	if mutations != callers {
+		t.Errorf("applied %d mutations, want %d", mutations, callers)
+	}
+}
+
+func TestExpiredRefreshTokenLogsInAgain(t *testing.T) {
+	alpha := newACLAlpha(t)
+	client := aclClient(t, alpha)
+	alpha.expire(true)
+
+	if _, err := client.Mutate(""); err != nil {
+		t.Fatalf("mutate with expired access and refresh JWTs: %v", err)
+	}
+	logins, mutations := alpha.state()
+	want := []loginRequest{
+		{UserID: "groot", Password: "password", Namespace: 2},
+		{RefreshToken: "refresh-1"},
+		{UserID: "groot", Password: "password", Namespace: 2},
+	}
+	if !reflect.DeepEqual(logins, want) {
+		t.Errorf("logins = %+v, want %+v", logins, want)
+	}
+	if mutations != 1 {
+		t.Errorf("applied %d mutations, want 1", mutations)
+	}
+}
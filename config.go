@@ -0,0 +1,130 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+)
+
+// Scenario describes the shape of a generated dataset.
+type Scenario struct {
+	Seed       int64          `json:"seed"` // 0 picks a seed from the clock
+	Cities     int            `json:"cities"`
+	Categories int            `json:"categories"`
+	Customers  int            `json:"customers"`
+	Products   int            `json:"products"`
+	Invoices   int            `json:"invoices"` // upper bound of invoices, 0 means no limit
+	Repeats    []RepeatBucket `json:"repeats"`
+	XIDs       string         `json:"xids"`   // XIDsRandom or XIDsStable
+	Facets     []string       `json:"facets"` // edge predicates given facets, keys of EdgeFacets
+}
+
+// XID modes of a scenario.
+const (
+	XIDsRandom = "random" // UUIDv4 for every node
+	XIDsStable = "stable" // UUIDv5 of the name for cities and categories, UUIDv7 for invoices and order details
+)
+
+// RepeatBucket is one tier of customer purchase behaviour. Customers are
+// spread over the buckets in proportion to Weight; a customer in the bucket
+// places MinRepeat to MaxRepeat invoices of MinAmount to MaxAmount items each.
+type RepeatBucket struct {
+	Weight    int `json:"weight"`
+	MinRepeat int `json:"min_repeat"`
+	MaxRepeat int `json:"max_repeat"`
+	MinAmount int `json:"min_amount"`
+	MaxAmount int `json:"max_amount"`
+}
+
+// DefaultScenario returns the dataset shape the populator has always generated.
+func DefaultScenario() Scenario {
+	return Scenario{
+		Cities:     len(cityNames),
+		Categories: len(categoryNames),
+		Customers:  10000,
+		Products:   1000,
+		XIDs:       XIDsRandom,
+		Repeats: []RepeatBucket{
+			{Weight: 66, MinRepeat: 1, MaxRepeat: 1, MinAmount: 1, MaxAmount: 2},
+			{Weight: 30, MinRepeat: 1, MaxRepeat: 3, MinAmount: 1, MaxAmount: 3},
+			{Weight: 4, MinRepeat: 2, MaxRepeat: 8, MinAmount: 1, MaxAmount: 5},
+		},
+	}
+}
+
+// LoadScenario reads a JSON scenario from path. Fields missing from the file
+// keep their DefaultScenario value; unknown fields are rejected so a misspelt
+// one does not silently keep its default.
+func LoadScenario(path string) (scenario Scenario, err error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return scenario, err
+	}
+
+	scenario = DefaultScenario()
+	defaultRepeats := scenario.Repeats
+	scenario.Repeats = nil
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	if err = decoder.Decode(&scenario); err != nil {
+		return scenario, fmt.Errorf("parse %s: %w", path, err)
+	}
+	if decoder.More() {
+		return scenario, fmt.Errorf("parse %s: unexpected data after the scenario", path)
+	}
+	if scenario.Repeats == nil {
+		scenario.Repeats = defaultRepeats
+	}
+
+	return scenario, nil
+}
+
+// Validate checks that every generator can be fed from the scenario.
+func (s Scenario) Validate() error {
+	if s.Cities < 0 || s.Categories < 0 || s.Customers < 0 || s.Products < 0 || s.Invoices < 0 {
+		return errors.New("counts must not be negative")
+	}
+	if s.Cities > len(cityNames) {
+		return fmt.Errorf("cities must be at most %d", len(cityNames))
+	}
+	if s.Categories > len(categoryNames) {
+		return fmt.Errorf("categories must be at most %d", len(categoryNames))
+	}
+	if s.Cities == 0 && (s.Customers > 0 || s.Products > 0) {
+		return errors.New("customers and products need at least one city")
+	}
+	if s.Categories == 0 && s.Products > 0 {
+		return errors.New("products need at least one category")
+	}
+	if s.Products == 0 && s.Customers > 0 {
+		return errors.New("customers need at least one product to place invoices")
+	}
+
+	if s.XIDs != XIDsRandom && s.XIDs != XIDsStable {
+		return fmt.Errorf("xids must be %q or %q", XIDsRandom, XIDsStable)
+	}
+	if err := validateFacetPredicates(s.Facets); err != nil {
+		return fmt.Errorf("facets: %w", err)
+	}
+
+	totalWeight := 0
+	for i, bucket := range s.Repeats {
+		if bucket.Weight < 0 {
+			return fmt.Errorf("repeats[%d]: weight must not be negative", i)
+		}
+		if bucket.MinRepeat < 1 || bucket.MaxRepeat < bucket.MinRepeat {
+			return fmt.Errorf("repeats[%d]: need 1 <= min_repeat <= max_repeat", i)
+		}
+		if bucket.MinAmount < 1 || bucket.MaxAmount < bucket.MinAmount {
+			return fmt.Errorf("repeats[%d]: need 1 <= min_amount <= max_amount", i)
+		}
+		totalWeight += bucket.Weight
+	}
+	if totalWeight == 0 && s.Customers > 0 {
+		return errors.New("repeats need a positive total weight")
+	}
+
+	return nil
+}
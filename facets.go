@@ -0,0 +1,170 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// Facet is a key-value pair attached to an edge.
+type Facet struct {
+	Key   string
+	Value interface{}
+}
+
+// EdgeFacets lists the facets each edge predicate can carry.
+var EdgeFacets = map[string][]string{
+	"destination":   {"since"},
+	"order":         {"channel"},
+	"order_product": {"quantity", "unit_price", "discount"},
+}
+
+// FacetPredicates are the edges given facets in this run, from the facets of
+// the scenario.
+var FacetPredicates = map[string]bool{}
+
+var (
+	orderChannels = []string{"web", "app", "store"}
+	discounts     = []decimal.Decimal{decimal.Zero, decimal.New(5, -2), decimal.New(1, -1), decimal.New(15, -2)}
+)
+
+// edgeFacets returns the facets built by facets when predicate carries facets
+// in this run, and nil otherwise.
+func edgeFacets(predicate string, facets func() []Facet) []Facet {
+	if !FacetPredicates[predicate] {
+		return nil
+	}
+	return facets()
+}
+
+// destinationFacets returns the date the customer has lived in its city
+// since, some day before the purchases of February 2022.
+func destinationFacets() []Facet {
+	since := time.Date(2019, time.January, 1, 0, 0, 0, 0, time.UTC).AddDate(0, 0, facetRng.Intn(3*365))
+	return []Facet{{Key: "since", Value: since}}
+}
+
+// orderFacets returns the sales channel of an invoice.
+func orderFacets() []Facet {
+	return []Facet{{Key: "channel", Value: orderChannels[facetRng.Intn(len(orderChannels))]}}
+}
+
+// orderProductFacets returns the quantity, unit price and discount of the
+// product of an order detail.
+func orderProductFacets(quantity int64, unitPrice decimal.Decimal) []Facet {
+	return []Facet{
+		{Key: "quantity", Value: quantity},
+		{Key: "unit_price", Value: unitPrice},
+		{Key: "discount", Value: discounts[facetRng.Intn(len(discounts))]},
+	}
+}
+
+// validateFacetPredicates checks that every predicate carries facets.
+func validateFacetPredicates(predicates []string) error {
+	for _, predicate := range predicates {
+		if _, ok := EdgeFacets[predicate]; !ok {
+			supported := make([]string, 0, len(EdgeFacets))
+			for name := range EdgeFacets {
+				supported = append(supported, name)
+			}
+			sort.Strings(supported)
+			return fmt.Errorf("no facets for predicate %q, expected one of %s", predicate, strings.Join(supported, ", "))
+		}
+	}
+	return nil
+}
+
+// FormatFacets encodes facets as the N-Quads facet list, with a leading space,
+// or returns "" when there are none.
+func FormatFacets(facets []Facet) string {
+	if len(facets) == 0 {
+		return ""
+	}
+	parts := make([]string, len(facets))
+	for i, facet := range facets {
+		parts[i] = facet.Key + "=" + facetLiteral(facet.Value)
+	}
+	return " (" + strings.Join(parts, ", ") + ")"
+}
+
+// facetLiteral encodes a facet value. Strings are quoted, everything else is
+// bare; decimals keep a fractional part so Dgraph stores them as floats.
+func facetLiteral(value interface{}) string {
+	if b, ok := value.(bool); ok {
+		return strconv.FormatBool(b)
+	}
+	lexical, datatype := literalForm(value)
+	switch datatype {
+	case "":
+		return `"` + EscapeLiteral(lexical) + `"`
+	case TypeFloat:
+		if !strings.ContainsAny(lexical, ".eE") {
+			lexical += ".0"
+		}
+	}
+	return lexical
+}
+
+// parseFacets parses the facet list at the start of s, if any.
+func parseFacets(s string) (facets []Facet, rest string, err error) {
+	if !strings.HasPrefix(s, "(") {
+		return nil, s, nil
+	}
+	rest = strings.TrimLeft(s[1:], " \t")
+
+	for !strings.HasPrefix(rest, ")") {
+		eq := strings.IndexByte(rest, '=')
+		if eq < 0 {
+			return nil, s, errors.New("expected key=value facet")
+		}
+		facet := Facet{Key: strings.TrimSpace(rest[:eq])}
+		rest = strings.TrimLeft(rest[eq+1:], " \t")
+
+		if strings.HasPrefix(rest, `"`) {
+			if facet.Value, rest, err = parseLiteral(rest); err != nil {
+				return nil, s, fmt.Errorf("facet %s: %w", facet.Key, err)
+			}
+		} else {
+			end := strings.IndexAny(rest, ",)")
+			if end < 0 {
+				return nil, s, errUnterminated
+			}
+			if facet.Value, err = parseFacetValue(strings.TrimSpace(rest[:end])); err != nil {
+				return nil, s, fmt.Errorf("facet %s: %w", facet.Key, err)
+			}
+			rest = rest[end:]
+		}
+		facets = append(facets, facet)
+
+		rest = strings.TrimLeft(rest, " \t")
+		if strings.HasPrefix(rest, ",") {
+			rest = strings.TrimLeft(rest[1:], " \t")
+		} else if !strings.HasPrefix(rest, ")") {
+			return nil, s, errUnterminated
+		}
+	}
+	return facets, strings.TrimLeft(rest[1:], " \t"), nil
+}
+
+// parseFacetValue decodes a bare facet value into the Go type facetLiteral
+// takes: int64, decimal.Decimal, time.Time, or bool.
+func parseFacetValue(lexical string) (interface{}, error) {
+	if i, err := strconv.ParseInt(lexical, 10, 64); err == nil {
+		return i, nil
+	}
+	if d, err := decimal.NewFromString(lexical); err == nil {
+		return d, nil
+	}
+	if t, err := time.Parse(time.RFC3339, lexical); err == nil {
+		return t, nil
+	}
+	if b, err := strconv.ParseBool(lexical); err == nil {
+		return b, nil
+	}
+	return nil, fmt.Errorf("invalid facet value %q", lexical)
+}
@@ -1,6 +1,6 @@
 module github.com/samaita/dgraph-populator
 
-go 1.17
+go 1.18
 
 require (
 	github.com/bxcodec/faker/v3 v3.8.0
@@ -0,0 +1,301 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"strconv"
+)
+
+// NestedPredicates are the edges to nodes owned by their subject. JSON output
+// nests the target object inside its subject; every other edge refers to its
+// target by uid.
+var NestedPredicates = map[string]bool{
+	"order":        true,
+	"order_detail": true,
+}
+
+// listPredicates are the predicates always written as JSON arrays.
+var listPredicates = func() map[string]bool {
+	lists := make(map[string]bool)
+	for _, predicate := range Predicates {
+		lists[predicate.Name] = predicate.List
+	}
+	return lists
+}()
+
+// jsonDoc groups quads into the node objects of a Dgraph JSON mutation.
+type jsonDoc struct {
+	nodes map[string]*jsonNode
+	order []*jsonNode // nodes in order of appearance
+}
+
+type jsonNode struct {
+	key    string
+	fields []*jsonField
+	nested bool // written inside the node owning it
+}
+
+type jsonField struct {
+	predicate string
+	values    []interface{} // literals, or jsonEdge for edges
+}
+
+// jsonEdge is an edge to a node nested in full, or referred to by uid when
+// node is nil. Its facets are written in the target object as predicate|key.
+type jsonEdge struct {
+	node   *jsonNode
+	uid    string
+	facets []Facet
+}
+
+func newJSONDoc() *jsonDoc {
+	return &jsonDoc{nodes: make(map[string]*jsonNode)}
+}
+
+func (d *jsonDoc) node(key string) *jsonNode {
+	node, ok := d.nodes[key]
+	if !ok {
+		node = &jsonNode{key: key}
+		d.nodes[key] = node
+		d.order = append(d.order, node)
+	}
+	return node
+}
+
+func (d *jsonDoc) add(quad Quad) {
+	node := d.node(quad.Subject)
+	var value interface{} = quad.Value
+	if quad.IsEdge() {
+		edge := jsonEdge{uid: quad.Object, facets: quad.Facets}
+		if NestedPredicates[quad.Predicate] {
+			if target := d.node(quad.Object); !target.nested && target != node {
+				target.nested = true
+				edge.node = target
+			}
+		}
+		value = edge
+	}
+
+	for _, field := range node.fields {
+		if field.predicate == quad.Predicate {
+			field.values = append(field.values, value)
+			return
+		}
+	}
+	node.fields = append(node.fields, &jsonField{predicate: quad.Predicate, values: []interface{}{value}})
+}
+
+// encode writes every node not nested in another one to w, one object per
+// line, separated by commas. first tells whether no object precedes them.
+func (d *jsonDoc) encode(w *bufio.Writer, first bool) error {
+	for _, node := range d.order {
+		if node.nested {
+			continue
+		}
+		if !first {
+			w.WriteString(",\n")
+		}
+		first = false
+		if err := node.encode(w, "", nil); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// encode writes the node as an object, with the facets of the edge predicate
+// leading to it.
+func (n *jsonNode) encode(w *bufio.Writer, predicate string, facets []Facet) error {
+	w.WriteString(`{"uid":`)
+	writeJSONString(w, jsonUID(n.key))
+	if err := encodeJSONFacets(w, predicate, facets); err != nil {
+		return err
+	}
+	for _, field := range n.fields {
+		w.WriteByte(',')
+		writeJSONString(w, field.predicate)
+		w.WriteByte(':')
+
+		list := listPredicates[field.predicate] || len(field.values) > 1
+		if list {
+			w.WriteByte('[')
+		}
+		for i, value := range field.values {
+			if i > 0 {
+				w.WriteByte(',')
+			}
+			if err := encodeJSONValue(w, field.predicate, value); err != nil {
+				return err
+			}
+		}
+		if list {
+			w.WriteByte(']')
+		}
+	}
+	return w.WriteByte('}')
+}
+
+func encodeJSONValue(w *bufio.Writer, predicate string, value interface{}) error {
+	edge, ok := value.(jsonEdge)
+	if !ok {
+		return encodeJSONLiteral(w, value)
+	}
+	if edge.node != nil {
+		return edge.node.encode(w, predicate, edge.facets)
+	}
+	w.WriteString(`{"uid":`)
+	writeJSONString(w, jsonUID(edge.uid))
+	if err := encodeJSONFacets(w, predicate, edge.facets); err != nil {
+		return err
+	}
+	return w.WriteByte('}')
+}
+
+func encodeJSONFacets(w *bufio.Writer, predicate string, facets []Facet) error {
+	for _, facet := range facets {
+		w.WriteByte(',')
+		writeJSONString(w, predicate+"|"+facet.Key)
+		w.WriteByte(':')
+		if err := encodeJSONLiteral(w, facet.Value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// encodeJSONLiteral writes numbers bare, decimals with a fractional part so
+// Dgraph reads them as floats, and everything else as a string.
+func encodeJSONLiteral(w *bufio.Writer, value interface{}) error {
+	if b, ok := value.(bool); ok {
+		_, err := w.WriteString(strconv.FormatBool(b))
+		return err
+	}
+
+	lexical, datatype := literalForm(value)
+	switch datatype {
+	case TypeInt:
+		_, err := w.WriteString(lexical)
+		return err
+	case TypeFloat:
+		_, err := w.WriteString(facetLiteral(value))
+		return err
+	}
+	return writeJSONString(w, lexical)
+}
+
+func writeJSONString(w *bufio.Writer, s string) error {
+	data, err := json.Marshal(s)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(data)
+	return err
+}
+
+// jsonUID returns the uid of a node key in a JSON mutation.
+func jsonUID(key string) string {
+	if IsUID(key) {
+		return key
+	}
+	return "_:" + key
+}
+
+// EncodeJSON returns quads as the JSON array of objects of a Dgraph mutation.
+func EncodeJSON(w io.Writer, quads []Quad) error {
+	doc := newJSONDoc()
+	for _, quad := range quads {
+		doc.add(quad)
+	}
+
+	buf := bufio.NewWriter(w)
+	buf.WriteString("[\n")
+	if err := doc.encode(buf, true); err != nil {
+		return err
+	}
+	buf.WriteString("\n]")
+	return buf.Flush()
+}
+
+// JSONWriter encodes quads as a JSON array of node objects, as read by the
+// Dgraph live loader. Consecutive quads are grouped into one object per node,
+// with the nodes of NestedPredicates inside their owner; the object is written
+// as soon as a quad about another node starts, so only one node tree is held
+// in memory. Dgraph merges the objects sharing a blank node.
+type JSONWriter struct {
+	buf     *bufio.Writer
+	closer  io.Closer
+	doc     *jsonDoc
+	started bool // whether the opening bracket was written
+	written bool // whether an object was written
+}
+
+// NewJSONWriter returns a buffered JSON writer on top of w. Closing the
+// writer also closes w when it is an io.Closer.
+func NewJSONWriter(w io.Writer) *JSONWriter {
+	jw := &JSONWriter{buf: bufio.NewWriterSize(w, 256*1024), doc: newJSONDoc()}
+	jw.closer, _ = w.(io.Closer)
+	return jw
+}
+
+// WriteQuad implements DatasetWriter. A quad about a node neither grouped so
+// far nor nested in one writes out the objects grouped so far.
+func (jw *JSONWriter) WriteQuad(quad Quad) error {
+	if _, ok := jw.doc.nodes[quad.Subject]; !ok {
+		if err := jw.encode(); err != nil {
+			return err
+		}
+	}
+	jw.doc.add(quad)
+	return nil
+}
+
+// encode writes the objects grouped so far to the buffer and starts a new
+// group.
+func (jw *JSONWriter) encode() error {
+	if !jw.started {
+		jw.buf.WriteString("[\n")
+		jw.started = true
+	}
+	if len(jw.doc.order) == 0 {
+		return nil
+	}
+	if err := jw.doc.encode(jw.buf, !jw.written); err != nil {
+		return err
+	}
+	jw.written = true
+	jw.doc = newJSONDoc()
+	return nil
+}
+
+// Flush implements DatasetWriter. It writes every node grouped so far; quads
+// written afterwards about the same node start a new object with its uid.
+func (jw *JSONWriter) Flush() error {
+	if err := jw.encode(); err != nil {
+		return err
+	}
+	return jw.buf.Flush()
+}
+
+// StartPhase implements PhaseWriter. The output so far is flushed, so a
+// phase begins with a new object.
+func (jw *JSONWriter) StartPhase(phase string) error {
+	return jw.Flush()
+}
+
+// Close implements DatasetWriter.
+func (jw *JSONWriter) Close() error {
+	err := jw.Flush()
+	if err == nil {
+		_, err = jw.buf.WriteString("\n]\n")
+	}
+	if flushErr := jw.buf.Flush(); err == nil {
+		err = flushErr
+	}
+	if jw.closer != nil {
+		if closeErr := jw.closer.Close(); err == nil {
+			err = closeErr
+		}
+	}
+	return err
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/shopspring/decimal"
+)
+
+// decodeJSON decodes data keeping numbers as written.
+func decodeJSON(t *testing.T, data []byte) interface{} {
+	t.Helper()
+	var v interface{}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	if err := dec.Decode(&v); err != nil {
+		t.Fatalf("decode %s: %v", data, err)
+	}
+	return v
+}
+
+func TestJSONWriter(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		quads   []Quad
+		flushAt int // number of quads written before Flush, or 0
+		want    string
+	}{
+		{
+			name: "nested orders and references",
+			quads: []Quad{
+				{Subject: "C1", Predicate: "name", Value: "Ann"},
+				{Subject: "C1", Predicate: "destination", Object: "A1"},
+				{Subject: "C1", Predicate: "order", Object: "IV1"},
+				{Subject: "IV1", Predicate: "order_detail", Object: "IT2"},
+				{Subject: "IT2", Predicate: "order_amount", Value: int64(3)},
+				{Subject: "IT2", Predicate: "order_product", Object: "P1"},
+			},
+			want: `[{"uid":"_:C1","name":"Ann","destination":{"uid":"_:A1"},"order":[{"uid":"_:IV1",
+				"order_detail":[{"uid":"_:IT2","order_amount":3,"order_product":{"uid":"_:P1"}}]}]}]`,
+		},
+		{
+			name: "single-valued list predicate",
+			quads: []Quad{
+				{Subject: "IV1", Predicate: "order_detail", Object: "IT2"},
+			},
+			want: `[{"uid":"_:IV1","order_detail":[{"uid":"_:IT2"}]}]`,
+		},
+		{
+			name: "facets in the target object",
+			quads: []Quad{
+				{Subject: "C1", Predicate: "destination", Object: "A1", Facets: []Facet{{Key: "since", Value: int64(2020)}}},
+				{Subject: "C1", Predicate: "order", Object: "IV1", Facets: []Facet{{Key: "channel", Value: "web"}}},
+				{Subject: "IV1", Predicate: "name", Value: "first"},
+			},
+			want: `[{"uid":"_:C1","destination":{"uid":"_:A1","destination|since":2020},
+				"order":[{"uid":"_:IV1","order|channel":"web","name":"first"}]}]`,
+		},
+		{
+			name: "decimals keep a fractional part",
+			quads: []Quad{
+				{Subject: "P1", Predicate: "price", Value: decimal.NewFromInt(12)},
+				{Subject: "P1", Predicate: "commission_amount", Value: decimal.RequireFromString("0.75")},
+				{Subject: "P1", Predicate: "commission_percentage", Value: 5},
+			},
+			want: `[{"uid":"_:P1","price":12.0,"commission_amount":0.75,"commission_percentage":5}]`,
+		},
+		{
+			name: "uid keys",
+			quads: []Quad{
+				{Subject: "0x1a", Predicate: "destination", Object: "0x2b"},
+				{Subject: "0x1a", Predicate: "name", Value: "Ann"},
+			},
+			want: `[{"uid":"0x1a","destination":{"uid":"0x2b"},"name":"Ann"}]`,
+		},
+		{
+			name: "node seen again after Flush",
+			quads: []Quad{
+				{Subject: "C1", Predicate: "name", Value: "Ann"},
+				{Subject: "C1", Predicate: "order", Object: "IV1"},
+			},
+			flushAt: 1,
+			want:    `[{"uid":"_:C1","name":"Ann"},{"uid":"_:C1","order":[{"uid":"_:IV1"}]}]`,
+		},
+		{
+			name: "node seen again after another node",
+			quads: []Quad{
+				{Subject: "C1", Predicate: "name", Value: "Ann"},
+				{Subject: "C2", Predicate: "name", Value: "Bob"},
+				{Subject: "C1", Predicate: "order", Object: "IV1"},
+			},
+			want: `[{"uid":"_:C1","name":"Ann"},{"uid":"_:C2","name":"Bob"},{"uid":"_:C1","order":[{"uid":"_:IV1"}]}]`,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			w := NewJSONWriter(&buf)
+			for i, quad := range tc.quads {
+				if i == tc.flushAt && i > 0 {
+					if err := w.Flush(); err != nil {
+						t.Fatal(err)
+					}
+				}
+				if err := w.WriteQuad(quad); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if err := w.Close(); err != nil {
+				t.Fatal(err)
+			}
+
+			if got, want := decodeJSON(t, buf.Bytes()), decodeJSON(t, []byte(tc.want)); !reflect.DeepEqual(got, want) {
+				t.Errorf("wrote\n%s\nwant\n%s", buf.Bytes(), tc.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"testing"
+)
+
+func FuzzEscapeLiteral(f *testing.F) {
+	for _, s := range []string{
+		"",
+		"Queen Jewell Pouros",
+		`say "hi"`,
+		`C:\path\to`,
+		"line\nbreak\r\ttab\b\f",
+		"\x00\x1f\x7f",
+		"é ß 中 🙂 \u2028 \ufeff",
+		"\xff\xfe invalid",
+		`\u0041 not an escape`,
+	} {
+		f.Add(s)
+	}
+
+	f.Fuzz(func(t *testing.T, s string) {
+		// Invalid UTF-8 is written as one U+FFFD per bad byte.
+		want := string([]rune(s))
+
+		escaped := EscapeLiteral(s)
+		unescaped, err := UnescapeLiteral(escaped)
+		if err != nil {
+			t.Fatalf("UnescapeLiteral(%q): %v", escaped, err)
+		}
+		if unescaped != want {
+			t.Fatalf("UnescapeLiteral(EscapeLiteral(%q)) = %q, want %q", s, unescaped, want)
+		}
+
+		line := FormatQuad(Quad{Subject: "S1", Predicate: "name", Value: s})
+		quad, err := ParseQuad(line)
+		if err != nil {
+			t.Fatalf("ParseQuad(%q): %v", line, err)
+		}
+		if quad.Value != want {
+			t.Fatalf("ParseQuad(%q).Value = %q, want %q", line, quad.Value, want)
+		}
+	})
+}
+
+func TestSelfCheckLiterals(t *testing.T) {
+	if err := SelfCheckLiterals(2000, 42); err != nil {
+		t.Fatal(err)
+	}
+}
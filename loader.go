@@ -0,0 +1,417 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// LoadOptions tune how a Loader sends mutations.
+type LoadOptions struct {
+	BatchSize int           // quads per mutation
+	Workers   int           // mutations in flight at once
+	Queue     int           // batches waiting for a worker before writes block
+	Retries   int           // attempts after the first one for retryable errors
+	Backoff   time.Duration // delay before the first retry, doubled on each retry
+	Upsert    bool          // match existing nodes instead of creating new ones
+	Format    string        // mutation body, FormatRDF or FormatJSON
+}
+
+// DefaultLoadOptions returns the options used by the load command.
+func DefaultLoadOptions() LoadOptions {
+	return LoadOptions{
+		BatchSize: 1000,
+		Workers:   4,
+		Queue:     8,
+		Retries:   5,
+		Backoff:   100 * time.Millisecond,
+		Format:    FormatRDF,
+	}
+}
+
+// Loader is a DatasetWriter that sends quads to Dgraph as mutations of
+// BatchSize quads each. Batches are committed by a pool of workers; once the
+// queue is full WriteQuad blocks, so generation never outruns Dgraph.
+//
+// The UIDs Dgraph assigns to blank nodes are recorded in UIDs, and later
+// batches refer to those nodes by UID. A batch referring to a node created by
+// a batch still in flight waits for it to commit first.
+//
+// With a Checkpoint, batches it lists as committed are skipped and every
+// committed batch is recorded in it.
+//
+// In upsert mode every node is instead matched in an upsert block on its xid,
+// or on its name for ReferenceEntities, so loading the same dataset twice
+// updates the existing nodes. Quads are held back until the xid or name and
+// the dgraph.type of the nodes they mention have been written, and a node's
+// quads released together stay in one batch, which may then exceed
+// BatchSize.
+type Loader struct {
+	Client     *Client
+	Options    LoadOptions
+	UIDs       *UIDMap
+	Checkpoint *Checkpoint
+
+	batches int64 // mutations committed so far
+	quads   int64 // quads committed so far
+	skipped int64 // quads of batches skipped thanks to the checkpoint
+
+	batch  []Quad
+	next   int64 // index of the next batch
+	closed bool
+	owners map[string]*loadBatch // batch creating each blank node not yet committed
+
+	keys    map[string]*nodeKey // upsert keys of every node seen
+	waiting map[string][]Quad   // upsert quads held back until the node is ready
+
+	queue chan *loadBatch
+	wg    sync.WaitGroup
+
+	errOnce sync.Once
+	err     error
+	failed  chan struct{}
+}
+
+type loadBatch struct {
+	index int64
+	query string // upsert query block, empty for plain mutations
+	rdf   string
+	json  string // JSON node objects, instead of rdf
+	size  int
+	done  chan struct{} // closed once the batch is committed or dropped
+}
+
+// committed stands in as the owner of nodes known to be committed.
+var committed = &loadBatch{done: make(chan struct{})}
+
+// committedBefore stands in as the owner of nodes created by a batch skipped
+// thanks to the checkpoint, whose UID is not in UIDs.
+var committedBefore = &loadBatch{done: make(chan struct{})}
+
+func init() {
+	close(committed.done)
+	close(committedBefore.done)
+}
+
+// NewLoader starts the workers of a loader committing through client. uids
+// holds the nodes of earlier loads, pass NewUIDMap() to start afresh.
+func NewLoader(client *Client, opts LoadOptions, uids *UIDMap) *Loader {
+	l := &Loader{
+		Client:  client,
+		Options: opts,
+		UIDs:    uids,
+		owners:  make(map[string]*loadBatch),
+		keys:    make(map[string]*nodeKey),
+		waiting: make(map[string][]Quad),
+		queue:   make(chan *loadBatch, opts.Queue),
+		failed:  make(chan struct{}),
+	}
+	for i := 0; i < opts.Workers; i++ {
+		l.wg.Add(1)
+		go l.work()
+	}
+	return l
+}
+
+// Batches returns the number of mutations committed so far.
+func (l *Loader) Batches() int64 {
+	return atomic.LoadInt64(&l.batches)
+}
+
+// Quads returns the number of quads committed so far.
+func (l *Loader) Quads() int64 {
+	return atomic.LoadInt64(&l.quads)
+}
+
+// Skipped returns the number of quads skipped as already committed.
+func (l *Loader) Skipped() int64 {
+	return l.skipped
+}
+
+// WriteQuad implements DatasetWriter.
+func (l *Loader) WriteQuad(quad Quad) error {
+	if !l.Options.Upsert {
+		return l.add(quad)
+	}
+
+	key := l.key(quad.Subject)
+	wasReady := key.ready()
+	key.observe(quad)
+	l.place(quad)
+	if !wasReady && key.ready() {
+		// The quads held back include the xid or name the node is looked
+		// up on. They go in the batch of the quad making it ready, or that
+		// batch would create the node without them and the next one would
+		// not find it.
+		held := l.waiting[quad.Subject]
+		delete(l.waiting, quad.Subject)
+		for _, quad := range held {
+			l.place(quad)
+		}
+	}
+	if len(l.batch) >= l.Options.BatchSize {
+		return l.Flush()
+	}
+	return nil
+}
+
+func (l *Loader) key(label string) *nodeKey {
+	key, ok := l.keys[label]
+	if !ok {
+		key = &nodeKey{}
+		l.keys[label] = key
+	}
+	return key
+}
+
+// place appends an upsert quad to the batch without flushing it, or holds it
+// back until the first node it mentions that cannot be looked up yet is ready.
+func (l *Loader) place(quad Quad) {
+	for _, label := range []string{quad.Subject, quad.Object} {
+		if label == "" || IsUID(label) || l.key(label).ready() {
+			continue
+		}
+		l.waiting[label] = append(l.waiting[label], quad)
+		return
+	}
+	l.batch = append(l.batch, quad)
+}
+
+func (l *Loader) add(quad Quad) error {
+	l.batch = append(l.batch, quad)
+	if len(l.batch) >= l.Options.BatchSize {
+		return l.Flush()
+	}
+	return nil
+}
+
+// Flush queues the pending batch. It blocks while the queue is full or while
+// a batch it depends on is in flight, and returns the error of the first
+// failed mutation.
+func (l *Loader) Flush() error {
+	if len(l.batch) == 0 {
+		return l.failure()
+	}
+
+	quads := l.batch
+	l.batch = nil
+	batch := &loadBatch{index: l.next, size: len(quads), done: make(chan struct{})}
+	l.next++
+	if l.Checkpoint != nil && l.Checkpoint.Done(batch.index) {
+		l.skip(quads)
+		return l.failure()
+	}
+
+	for i := range quads {
+		quad := &quads[i]
+		var err error
+		if quad.Subject, err = l.resolve(batch, quad.Subject); err != nil {
+			return err
+		}
+		if quad.IsEdge() {
+			if quad.Object, err = l.resolve(batch, quad.Object); err != nil {
+				return err
+			}
+		}
+	}
+
+	switch {
+	case l.Options.Upsert:
+		batch.query, batch.rdf = buildUpsert(quads, l.keys)
+	case l.Options.Format == FormatJSON:
+		var nodes strings.Builder
+		if err := EncodeJSON(&nodes, quads); err != nil {
+			return err
+		}
+		batch.json = nodes.String()
+	default:
+		var rdf strings.Builder
+		for _, quad := range quads {
+			rdf.WriteString(FormatQuad(quad))
+			rdf.WriteByte('\n')
+		}
+		batch.rdf = rdf.String()
+	}
+
+	select {
+	case l.queue <- batch:
+		return l.failure()
+	case <-l.failed:
+		return l.err
+	}
+}
+
+// skip records the nodes of a batch committed by an earlier load. In upsert
+// mode they are looked up like any committed node; otherwise later batches
+// link to them by UID, and resolve fails on those missing from UIDs rather
+// than create them again.
+func (l *Loader) skip(quads []Quad) {
+	l.skipped += int64(len(quads))
+	for _, quad := range quads {
+		for _, key := range []string{quad.Subject, quad.Object} {
+			if key == "" || IsUID(key) {
+				continue
+			}
+			if _, ok := l.owners[key]; ok {
+				continue
+			}
+			if l.Options.Upsert {
+				l.owners[key] = committed
+			} else if _, ok := l.UIDs.Get(key); !ok {
+				l.owners[key] = committedBefore
+			}
+		}
+	}
+}
+
+// Close queues the pending batch and waits for every queued batch.
+func (l *Loader) Close() error {
+	if l.closed {
+		return l.failure()
+	}
+	l.closed = true
+
+	err := l.Flush()
+	if err == nil && len(l.waiting) > 0 {
+		err = fmt.Errorf("%d nodes were never given an xid or name and a dgraph.type to upsert on", len(l.waiting))
+	}
+	close(l.queue)
+	l.wg.Wait()
+	if err != nil {
+		return err
+	}
+	return l.failure()
+}
+
+// resolve returns the UID of the node key when it is known, waiting for the
+// batch that creates it if needed. Nodes new to batch, and every node in
+// upsert mode, keep their label.
+func (l *Loader) resolve(batch *loadBatch, key string) (string, error) {
+	if IsUID(key) {
+		return key, nil
+	}
+	if uid, ok := l.UIDs.Get(key); ok && !l.Options.Upsert {
+		delete(l.owners, key)
+		return uid, nil
+	}
+
+	owner, ok := l.owners[key]
+	if !ok {
+		l.owners[key] = batch
+		return key, nil
+	}
+	if owner == batch || owner == committed {
+		return key, nil
+	}
+	if owner == committedBefore {
+		return "", fmt.Errorf("_:%s was created by a batch committed before the load was resumed, but has no UID in the xidmap", key)
+	}
+
+	<-owner.done
+	if err := l.failure(); err != nil {
+		return "", err
+	}
+	if l.Options.Upsert {
+		l.owners[key] = committed
+		return key, nil
+	}
+
+	delete(l.owners, key)
+	if uid, ok := l.UIDs.Get(key); ok {
+		return uid, nil
+	}
+	return "", fmt.Errorf("no UID assigned to _:%s", key)
+}
+
+func (l *Loader) failure() error {
+	select {
+	case <-l.failed:
+		return l.err
+	default:
+		return nil
+	}
+}
+
+func (l *Loader) fail(err error) {
+	l.errOnce.Do(func() {
+		l.err = err
+		close(l.failed)
+	})
+}
+
+func (l *Loader) work() {
+	defer l.wg.Done()
+	for batch := range l.queue {
+		if l.failure() != nil {
+			close(batch.done)
+			continue
+		}
+		err := l.commit(batch)
+		if err == nil && l.Checkpoint != nil {
+			err = l.Checkpoint.Mark(batch.index)
+		}
+		close(batch.done)
+		if err != nil {
+			l.fail(err)
+			continue
+		}
+
+		batches := atomic.AddInt64(&l.batches, 1)
+		quads := atomic.AddInt64(&l.quads, int64(batch.size))
+		if batches%100 == 0 {
+			log.Printf("Committed %d quads in %d mutations", quads, batches)
+		}
+	}
+}
+
+// commit sends one batch and records the UIDs assigned to its blank nodes,
+// retrying aborted transactions and server errors with exponential backoff.
+// Upsert blocks match the nodes they create when sent again, so they are
+// retried after an UnconfirmedError too.
+func (l *Loader) commit(batch *loadBatch) error {
+	backoff := l.Options.Backoff
+	for attempt := 0; ; attempt++ {
+		var result MutationResult
+		var err error
+		switch {
+		case batch.query != "":
+			_, err = l.Client.Upsert(batch.query, batch.rdf)
+		case batch.json != "":
+			result, err = l.Client.MutateJSON(batch.json)
+		default:
+			result, err = l.Client.Mutate(batch.rdf)
+		}
+		if err == nil {
+			return l.UIDs.Add(result.Data.Uids)
+		}
+		var unconfirmed *UnconfirmedError
+		retryable := IsRetryable(err) || batch.query != "" && errors.As(err, &unconfirmed)
+		if attempt >= l.Options.Retries || !retryable {
+			return err
+		}
+
+		log.Printf("Mutation failed, retrying in %s: %s", backoff, err)
+		time.Sleep(backoff)
+		backoff *= 2
+	}
+}
+
+// LoadFile writes every quad of the dataset file at path to w and closes w.
+func LoadFile(w DatasetWriter, path string) error {
+	err := scanQuads(path, func(lineNo int, line string) error {
+		quad, err := ParseQuad(line)
+		if err != nil {
+			return fmt.Errorf("%s:%d: %w", path, lineNo, err)
+		}
+		return w.WriteQuad(quad)
+	})
+	if err != nil {
+		w.Close()
+		return err
+	}
+	return w.Close()
+}
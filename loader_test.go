@@ -0,0 +1,485 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeAlpha stands in for the /mutate endpoint of an Alpha. It commits RDF
+// mutations by assigning a fresh UID to every blank node, and rejects
+// references to UIDs it never assigned. Upsert blocks bind their variables
+// to the first committed node matching eq(xid) or eq(name) and type(), and
+// create a node for every variable left empty. respond, when set, may answer
+// a request itself instead, e.g. to fail it.
+type fakeAlpha struct {
+	*httptest.Server
+	respond func(attempt int, w http.ResponseWriter, r *http.Request) bool
+
+	mu       sync.Mutex
+	attempts int
+	nodes    map[string]string // UID to the label that created it
+	quads    []Quad            // committed quads, with UIDs for nodes
+}
+
+func newFakeAlpha(t *testing.T) *fakeAlpha {
+	a := &fakeAlpha{nodes: make(map[string]string)}
+	a.Server = httptest.NewServer(http.HandlerFunc(a.serve))
+	t.Cleanup(a.Close)
+	return a
+}
+
+// committed returns the nodes and quads committed, and the number of requests
+// received so far.
+func (a *fakeAlpha) committed() (nodes map[string]string, quads []Quad, attempts int) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.nodes, a.quads, a.attempts
+}
+
+func (a *fakeAlpha) serve(w http.ResponseWriter, r *http.Request) {
+	data, _ := io.ReadAll(r.Body)
+
+	a.mu.Lock()
+	a.attempts++
+	attempt := a.attempts
+	respond := a.respond
+	a.mu.Unlock()
+
+	if respond != nil && respond(attempt, w, r) {
+		return
+	}
+	if r.URL.Path != "/mutate" || r.URL.Query().Get("commitNow") != "true" {
+		http.Error(w, "unexpected request "+r.URL.String(), http.StatusNotFound)
+		return
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	body := string(data)
+	if strings.HasPrefix(body, "upsert {") {
+		body = a.bindUpsert(body)
+	}
+	var quads []Quad
+	for _, line := range strings.Split(body, "\n") {
+		if line = strings.TrimSpace(line); !strings.HasSuffix(line, " .") {
+			continue
+		}
+		quad, err := ParseQuad(line)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		quads = append(quads, quad)
+	}
+
+	uids := make(map[string]string)
+	resolve := func(key string) (string, error) {
+		if IsUID(key) {
+			if _, ok := a.nodes[key]; !ok {
+				return "", fmt.Errorf("unknown uid %s", key)
+			}
+			return key, nil
+		}
+		if _, ok := uids[key]; !ok {
+			uids[key] = fmt.Sprintf("0x%x", len(a.nodes)+len(uids)+1)
+		}
+		return uids[key], nil
+	}
+	for i := range quads {
+		var err error
+		if quads[i].Subject, err = resolve(quads[i].Subject); err == nil && quads[i].IsEdge() {
+			quads[i].Object, err = resolve(quads[i].Object)
+		}
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+	for label, uid := range uids {
+		a.nodes[uid] = label
+	}
+	a.quads = append(a.quads, quads...)
+
+	fmt.Fprint(w, `{"data":{"code":"Success","message":"Done","uids":{`)
+	first := true
+	for label, uid := range uids {
+		if !first {
+			fmt.Fprint(w, ",")
+		}
+		first = false
+		fmt.Fprintf(w, "%q:%q", label, uid)
+	}
+	fmt.Fprint(w, `}}}`)
+}
+
+var upsertVar = regexp.MustCompile(`(v\d+) as var\(func: eq\((xid|name), "((?:[^"\\]|\\.)*)"\), first: 1\)(?: @filter\(type\(([\w.]+)\)\))?`)
+
+// bindUpsert runs the query block of an upsert and returns its set block,
+// with every uid(v) replaced by the UID bound to v, or a blank node. a.mu
+// must be held.
+func (a *fakeAlpha) bindUpsert(body string) string {
+	set := body[strings.Index(body, "set {"):]
+	for _, match := range upsertVar.FindAllStringSubmatch(body, -1) {
+		v, predicate, typ := match[1], match[2], match[4]
+		value, _ := UnescapeLiteral(match[3])
+		node := "_:" + v
+		for _, quad := range a.quads {
+			if quad.Predicate == predicate && quad.Value == value && (typ == "" || a.hasType(quad.Subject, typ)) {
+				node = "<" + quad.Subject + ">"
+				break
+			}
+		}
+		set = strings.ReplaceAll(set, "uid("+v+")", node)
+	}
+	return set
+}
+
+func (a *fakeAlpha) hasType(uid, typ string) bool {
+	for _, quad := range a.quads {
+		if quad.Subject == uid && quad.Predicate == DgraphType && quad.Value == typ {
+			return true
+		}
+	}
+	return false
+}
+
+// testDataset returns customers who each place one invoice, shaped like the
+// generated dataset: invoices point back to customers written long before.
+func testDataset(customers int) []Quad {
+	var quads []Quad
+	for c := 1; c <= customers; c++ {
+		key := fmt.Sprintf("C%d", c)
+		quads = append(quads,
+			Quad{Subject: key, Predicate: "name", Value: "Customer " + key},
+			Quad{Subject: key, Predicate: "xid", Value: "xid-" + key},
+			Quad{Subject: key, Predicate: DgraphType, Value: "Customer"},
+		)
+	}
+	for c := 1; c <= customers; c++ {
+		customer, invoice := fmt.Sprintf("C%d", c), fmt.Sprintf("IV%d", c)
+		quads = append(quads,
+			Quad{Subject: customer, Predicate: "order", Object: invoice},
+			Quad{Subject: invoice, Predicate: "xid", Value: "xid-" + invoice},
+			Quad{Subject: invoice, Predicate: DgraphType, Value: "InvoiceOrder"},
+		)
+	}
+	return quads
+}
+
+func testLoadOptions() LoadOptions {
+	opts := DefaultLoadOptions()
+	opts.BatchSize = 7
+	opts.Backoff = time.Millisecond
+	return opts
+}
+
+func load(t *testing.T, client *Client, opts LoadOptions, quads []Quad) (*Loader, error) {
+	t.Helper()
+	l := NewLoader(client, opts, NewUIDMap())
+	for _, quad := range quads {
+		if err := l.WriteQuad(quad); err != nil {
+			l.Close()
+			return l, err
+		}
+	}
+	return l, l.Close()
+}
+
+func TestLoaderLinksBatchesByUID(t *testing.T) {
+	alpha := newFakeAlpha(t)
+	alpha.respond = func(attempt int, w http.ResponseWriter, r *http.Request) bool {
+		// Reorder the workers, so a batch would overtake the one it depends on.
+		time.Sleep(time.Duration(attempt%3) * time.Millisecond)
+		return false
+	}
+
+	quads := testDataset(50)
+	l, err := load(t, NewClient(alpha.URL), testLoadOptions(), quads)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if l.Quads() != int64(len(quads)) {
+		t.Errorf("committed %d quads, want %d", l.Quads(), len(quads))
+	}
+
+	// Every label must have created exactly one node, which the UID map
+	// points to.
+	nodes, committed, _ := alpha.committed()
+	labels := make(map[string]string)
+	for uid, label := range nodes {
+		if other, ok := labels[label]; ok {
+			t.Errorf("_:%s created twice, as %s and %s", label, other, uid)
+		}
+		labels[label] = uid
+		if got, _ := l.UIDs.Get(label); got != uid {
+			t.Errorf("UIDs[%s] = %q, want %q", label, got, uid)
+		}
+	}
+	if want := 100; len(labels) != want {
+		t.Errorf("created %d nodes, want %d", len(labels), want)
+	}
+	orders := 0
+	for _, quad := range committed {
+		if quad.Predicate == "order" {
+			orders++
+			if customer, invoice := nodes[quad.Subject], nodes[quad.Object]; customer[1:] != invoice[2:] {
+				t.Errorf("order links _:%s to _:%s", customer, invoice)
+			}
+		}
+	}
+	if orders != 50 {
+		t.Errorf("committed %d order edges, want 50", orders)
+	}
+}
+
+func TestLoaderUpsertsEveryNodeOnce(t *testing.T) {
+	quads := testDataset(10)
+	for size := 1; size <= 8; size++ {
+		alpha := newFakeAlpha(t)
+		opts := testLoadOptions()
+		opts.BatchSize = size
+		opts.Upsert = true
+
+		// Loading the dataset again must match every node instead of
+		// creating it anew.
+		for run := 0; run < 2; run++ {
+			if _, err := load(t, NewClient(alpha.URL), opts, quads); err != nil {
+				t.Fatalf("-batch %d: %v", size, err)
+			}
+		}
+
+		nodes, committed, _ := alpha.committed()
+		if len(nodes) != 20 {
+			t.Errorf("-batch %d: created %d nodes, want 20", size, len(nodes))
+		}
+		xids := make(map[string]map[string]bool)
+		types := make(map[string]bool)
+		for _, quad := range committed {
+			switch quad.Predicate {
+			case "xid":
+				if xids[quad.Subject] == nil {
+					xids[quad.Subject] = make(map[string]bool)
+				}
+				xids[quad.Subject][quad.Value.(string)] = true
+			case DgraphType:
+				types[quad.Subject] = true
+			}
+		}
+		for uid := range nodes {
+			if len(xids[uid]) != 1 || !types[uid] {
+				t.Errorf("-batch %d: node %s has xids %v and dgraph.type %t, want one xid and a type", size, uid, xids[uid], types[uid])
+			}
+		}
+	}
+}
+
+func TestLoaderRetries(t *testing.T) {
+	alpha := newFakeAlpha(t)
+	alpha.respond = func(attempt int, w http.ResponseWriter, r *http.Request) bool {
+		switch attempt {
+		case 1:
+			http.Error(w, "unavailable", http.StatusServiceUnavailable)
+		case 2:
+			fmt.Fprint(w, `{"errors":[{"message":"Transaction has been aborted. Please retry"}]}`)
+		default:
+			return false
+		}
+		return true
+	}
+
+	opts := testLoadOptions()
+	opts.Workers = 1
+	l, err := load(t, NewClient(alpha.URL), opts, testDataset(2))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, attempts := alpha.committed(); l.Batches() != 2 || attempts != 4 {
+		t.Errorf("committed %d batches in %d attempts, want 2 in 4", l.Batches(), attempts)
+	}
+}
+
+func TestLoaderStopsOnPermanentError(t *testing.T) {
+	alpha := newFakeAlpha(t)
+	alpha.respond = func(attempt int, w http.ResponseWriter, r *http.Request) bool {
+		http.Error(w, "bad mutation", http.StatusBadRequest)
+		return true
+	}
+
+	_, err := load(t, NewClient(alpha.URL), testLoadOptions(), testDataset(20))
+	var httpErr *HTTPError
+	if !errors.As(err, &httpErr) || httpErr.StatusCode != http.StatusBadRequest {
+		t.Fatalf("load error = %v, want a 400 HTTPError", err)
+	}
+	if _, _, attempts := alpha.committed(); attempts > testLoadOptions().Workers {
+		t.Errorf("sent %d mutations after a permanent error", attempts)
+	}
+}
+
+func TestLoaderDoesNotRetryUnconfirmedMutations(t *testing.T) {
+	alpha := newFakeAlpha(t)
+	alpha.respond = func(attempt int, w http.ResponseWriter, r *http.Request) bool {
+		// Never answer: the mutation reached Dgraph, the response is lost.
+		<-r.Context().Done()
+		return true
+	}
+
+	client := NewClient(alpha.URL)
+	client.HTTP.Timeout = 50 * time.Millisecond
+	opts := testLoadOptions()
+	opts.Workers = 1
+	_, err := load(t, client, opts, testDataset(1))
+
+	var unconfirmed *UnconfirmedError
+	if !errors.As(err, &unconfirmed) {
+		t.Fatalf("load error = %v, want an UnconfirmedError", err)
+	}
+	if _, _, attempts := alpha.committed(); attempts != 1 {
+		t.Errorf("sent the mutation %d times, want once", attempts)
+	}
+}
+
+func TestIsRetryable(t *testing.T) {
+	for _, tc := range []struct {
+		err  error
+		want bool
+	}{
+		{&HTTPError{Path: "/mutate", StatusCode: 503}, true},
+		{&HTTPError{Path: "/mutate", StatusCode: 400}, false},
+		{fmt.Errorf("/mutate: %w", DgraphError{Message: "Transaction has been aborted. Please retry"}), true},
+		{fmt.Errorf("/mutate: %w", DgraphError{Message: "invalid predicate"}), false},
+		{&UnconfirmedError{Path: "/mutate", Err: errors.New("timeout")}, false},
+	} {
+		if got := IsRetryable(tc.err); got != tc.want {
+			t.Errorf("IsRetryable(%v) = %t, want %t", tc.err, got, tc.want)
+		}
+	}
+}
+
+// resumeLoad loads quads with a checkpoint kept in dir, resuming from it when
+// resume is set.
+func resumeLoad(t *testing.T, alpha *fakeAlpha, dir string, uids *UIDMap, resume bool) (*Loader, error) {
+	t.Helper()
+	opts := testLoadOptions()
+	opts.Workers = 1
+	source := filepath.Join(dir, "dataset.rdf")
+	checkpoint := NewCheckpoint(source, "checksum", opts, filepath.Join(dir, "dataset.xidmap"))
+	if resume {
+		var err error
+		if checkpoint, err = LoadCheckpoint(source, "checksum", opts, filepath.Join(dir, "dataset.xidmap")); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	l := NewLoader(NewClient(alpha.URL), opts, uids)
+	l.Checkpoint = checkpoint
+	for _, quad := range testDataset(20) {
+		if err := l.WriteQuad(quad); err != nil {
+			l.Close()
+			return l, err
+		}
+	}
+	return l, l.Close()
+}
+
+func TestLoaderResumesFromCheckpoint(t *testing.T) {
+	alpha := newFakeAlpha(t)
+	alpha.respond = func(attempt int, w http.ResponseWriter, r *http.Request) bool {
+		if attempt == 4 {
+			http.Error(w, "interrupted", http.StatusBadRequest)
+			return true
+		}
+		return false
+	}
+	dir := t.TempDir()
+	uids, err := OpenUIDMap(filepath.Join(dir, "dataset.xidmap"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := resumeLoad(t, alpha, dir, uids, false); err == nil {
+		t.Fatal("interrupted load succeeded")
+	}
+	uids.Close()
+
+	if uids, err = OpenUIDMap(filepath.Join(dir, "dataset.xidmap")); err != nil {
+		t.Fatal(err)
+	}
+	defer uids.Close()
+	l, err := resumeLoad(t, alpha, dir, uids, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if l.Skipped() != 21 {
+		t.Errorf("skipped %d quads, want the 21 of the first 3 batches", l.Skipped())
+	}
+	if nodes, _, _ := alpha.committed(); len(nodes) != 40 {
+		t.Errorf("created %d nodes, want 40", len(nodes))
+	}
+}
+
+func TestLoaderRefusesToRecreateSkippedNodes(t *testing.T) {
+	alpha := newFakeAlpha(t)
+	alpha.respond = func(attempt int, w http.ResponseWriter, r *http.Request) bool {
+		if attempt == 4 {
+			http.Error(w, "interrupted", http.StatusBadRequest)
+			return true
+		}
+		return false
+	}
+	dir := t.TempDir()
+	if _, err := resumeLoad(t, alpha, dir, NewUIDMap(), false); err == nil {
+		t.Fatal("interrupted load succeeded")
+	}
+	nodes, _, _ := alpha.committed()
+	created := len(nodes)
+
+	// The UIDs of the first load are lost: its nodes must not be created
+	// again as blank nodes.
+	_, err := resumeLoad(t, alpha, dir, NewUIDMap(), true)
+	if err == nil || !strings.Contains(err.Error(), "no UID in the xidmap") {
+		t.Fatalf("resumed load error = %v, want a missing UID", err)
+	}
+	nodes, _, _ = alpha.committed()
+	for uid, label := range nodes {
+		for other, otherLabel := range nodes {
+			if uid != other && label == otherLabel {
+				t.Errorf("_:%s created twice, as %s and %s", label, uid, other)
+			}
+		}
+	}
+	if len(nodes) < created {
+		t.Errorf("lost nodes: %d, had %d", len(nodes), created)
+	}
+}
+
+func TestLoadCheckpointChecksXIDMap(t *testing.T) {
+	dir := t.TempDir()
+	source := filepath.Join(dir, "dataset.rdf")
+	opts := testLoadOptions()
+
+	if err := NewCheckpoint(source, "checksum", opts, "").Mark(0); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadCheckpoint(source, "checksum", opts, "/tmp/other.xidmap"); err == nil {
+		t.Error("resumed a load that ran without -xidmap")
+	}
+
+	if err := NewCheckpoint(source, "checksum", opts, "/tmp/dataset.xidmap").Mark(0); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadCheckpoint(source, "checksum", opts, "/tmp/other.xidmap"); err == nil {
+		t.Error("resumed a load with another -xidmap")
+	}
+	if _, err := LoadCheckpoint(source, "checksum", opts, "/tmp/dataset.xidmap"); err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"bytes"
+	"regexp"
+	"testing"
+)
+
+// generateRDF returns the N-Quads of the scenario.
+func generateRDF(t *testing.T, scenario Scenario) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	w := NewRDFWriter(&buf)
+	if err := Generate(w, scenario); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func testScenario() Scenario {
+	scenario := DefaultScenario()
+	scenario.Seed = 42
+	scenario.Customers = 200
+	scenario.Products = 50
+	return scenario
+}
+
+func TestGenerateIsReproducible(t *testing.T) {
+	for _, xids := range []string{XIDsRandom, XIDsStable} {
+		scenario := testScenario()
+		scenario.XIDs = xids
+
+		first := generateRDF(t, scenario)
+		// Draw from the generators in between, as another run would.
+		SetSeed(7)
+		FakeName()
+		NewXID()
+		if second := generateRDF(t, scenario); !bytes.Equal(first, second) {
+			t.Errorf("-xids %s: two runs with seed %d differ", xids, scenario.Seed)
+		}
+
+		scenario.Seed++
+		if other := generateRDF(t, scenario); bytes.Equal(first, other) {
+			t.Errorf("-xids %s: seeds %d and %d give the same dataset", xids, scenario.Seed-1, scenario.Seed)
+		}
+	}
+}
+
+var facetSuffix = regexp.MustCompile(`(?m) \([^)]*\) \.$`)
+
+func TestFacetsOnlyAddFacets(t *testing.T) {
+	scenario := testScenario()
+	plain := generateRDF(t, scenario)
+
+	scenario.Facets = []string{"destination", "order", "order_product"}
+	withFacets := generateRDF(t, scenario)
+	if bytes.Equal(plain, withFacets) {
+		t.Fatal("facets changed nothing")
+	}
+	if stripped := facetSuffix.ReplaceAll(withFacets, []byte(" .")); !bytes.Equal(plain, stripped) {
+		t.Error("the dataset with its facets removed differs from the dataset without facets")
+	}
+}
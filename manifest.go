@@ -0,0 +1,246 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Manifest describes a generated dataset: the scenario it was generated from
+// and what it holds, so a load can be verified against it.
+type Manifest struct {
+	Seed     int64            `json:"seed"`
+	Scenario Scenario         `json:"scenario"`
+	Quads    int64            `json:"quads"`
+	Entities map[string]int64 `json:"entities"` // nodes per entity value
+	Edges    map[string]int64 `json:"edges"`    // edges per predicate
+	Files    []ManifestFile   `json:"files"`
+}
+
+// ManifestFile is a dataset file of a manifest. The files of a per-entity
+// dataset are listed in load order, each with the phases it depends on. The
+// manifest file stores Path relative to its own directory, so it can be
+// verified from anywhere; in memory Path is relative to the working
+// directory.
+type ManifestFile struct {
+	Path      string   `json:"path"`
+	Size      int64    `json:"size"`
+	SHA256    string   `json:"sha256"`
+	Phase     string   `json:"phase,omitempty"`
+	DependsOn []string `json:"depends_on,omitempty"`
+}
+
+// ManifestPath returns the manifest file kept next to a dataset file.
+func ManifestPath(output string) string {
+	return output + ".manifest.json"
+}
+
+// NewManifest returns an empty manifest of a dataset generated from scenario.
+func NewManifest(scenario Scenario) *Manifest {
+	return &Manifest{
+		Seed:     scenario.Seed,
+		Scenario: scenario,
+		Entities: make(map[string]int64),
+		Edges:    make(map[string]int64),
+	}
+}
+
+// ReadManifest reads the manifest file at path, with the paths of its files
+// resolved against the directory of path.
+func ReadManifest(path string) (*Manifest, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	m := NewManifest(Scenario{})
+	if err := json.Unmarshal(data, m); err != nil {
+		return nil, fmt.Errorf("parse %s: %w", path, err)
+	}
+	for i, file := range m.Files {
+		if !filepath.IsAbs(file.Path) {
+			m.Files[i].Path = filepath.Join(filepath.Dir(path), file.Path)
+		}
+	}
+	return m, nil
+}
+
+// Count records quad in the manifest.
+func (m *Manifest) Count(quad Quad) {
+	m.Quads++
+	if quad.IsEdge() {
+		m.Edges[quad.Predicate]++
+	}
+	if quad.Predicate == Entity {
+		m.Entities[fmt.Sprint(quad.Value)]++
+	}
+}
+
+// AddFile records the size and checksum of the dataset file at path, holding
+// phase of the dataset or every phase when phase is empty. A pending output
+// is read from its temporary file.
+func (m *Manifest) AddFile(path, phase string) error {
+	name := PendingName(path)
+	info, err := os.Stat(name)
+	if err != nil {
+		return err
+	}
+	checksum, err := FileChecksum(name)
+	if err != nil {
+		return err
+	}
+	m.Files = append(m.Files, ManifestFile{
+		Path:      path,
+		Size:      info.Size(),
+		SHA256:    checksum,
+		Phase:     phase,
+		DependsOn: PhaseDependencies[phase],
+	})
+	return nil
+}
+
+// Write saves the manifest to a new output of path, committed with the
+// dataset files. The paths of the files are written relative to the
+// directory of path.
+func (m *Manifest) Write(path string) error {
+	dir, err := filepath.Abs(filepath.Dir(path))
+	if err != nil {
+		return err
+	}
+	saved := *m
+	saved.Files = make([]ManifestFile, len(m.Files))
+	for i, file := range m.Files {
+		abs, err := filepath.Abs(file.Path)
+		if err != nil {
+			return err
+		}
+		if file.Path, err = filepath.Rel(dir, abs); err != nil {
+			return err
+		}
+		saved.Files[i] = file
+	}
+
+	data, err := json.MarshalIndent(&saved, "", "  ")
+	if err != nil {
+		return err
+	}
+	return WriteOutput(path, append(data, '\n'))
+}
+
+// ManifestWriter records every quad written through it in a Manifest.
+type ManifestWriter struct {
+	DatasetWriter
+	Manifest *Manifest
+}
+
+// WriteQuad implements DatasetWriter.
+func (w ManifestWriter) WriteQuad(quad Quad) error {
+	w.Manifest.Count(quad)
+	return w.DatasetWriter.WriteQuad(quad)
+}
+
+// StartPhase implements PhaseWriter.
+func (w ManifestWriter) StartPhase(phase string) error {
+	return startPhase(w.DatasetWriter, phase)
+}
+
+// Mismatch is a count of a manifest that differs from the loaded data.
+type Mismatch struct {
+	Check    string
+	Expected int64
+	Actual   int64
+}
+
+func (m Mismatch) String() string {
+	return fmt.Sprintf("%s: expected %d, got %d", m.Check, m.Expected, m.Actual)
+}
+
+// VerifyCounts compares the entity and edge counts of m with the data served
+// by client. Every entity is counted both by its entity value and by its
+// dgraph.type, and every edge predicate by the sum of its count().
+func VerifyCounts(client *Client, m *Manifest) (checks int, mismatches []Mismatch, err error) {
+	type check struct {
+		name     string
+		alias    string // query block returning the count
+		block    string
+		expected int64
+	}
+	var list []check
+
+	for i, entity := range sortedKeys(m.Entities) {
+		e, t := fmt.Sprintf("e%d", i), fmt.Sprintf("t%d", i)
+		list = append(list,
+			check{
+				name:     fmt.Sprintf("%s %q", Entity, entity),
+				alias:    e,
+				block:    fmt.Sprintf("  %s(func: eq(%s, \"%s\")) { count: count(uid) }\n", e, Entity, EscapeLiteral(entity)),
+				expected: m.Entities[entity],
+			},
+			check{
+				name:     fmt.Sprintf("%s %s", DgraphType, TypeName(entity)),
+				alias:    t,
+				block:    fmt.Sprintf("  %s(func: type(%s)) { count: count(uid) }\n", t, TypeName(entity)),
+				expected: m.Entities[entity],
+			})
+	}
+	for i, predicate := range sortedKeys(m.Edges) {
+		p := fmt.Sprintf("p%d", i)
+		list = append(list, check{
+			name:     fmt.Sprintf("edges %s", predicate),
+			alias:    p,
+			block:    fmt.Sprintf("  var(func: has(%s)) { c%d as count(%s) }\n  %s() { count: sum(val(c%d)) }\n", predicate, i, predicate, p, i),
+			expected: m.Edges[predicate],
+		})
+	}
+
+	var query strings.Builder
+	query.WriteString("{\n")
+	for _, c := range list {
+		query.WriteString(c.block)
+	}
+	query.WriteString("}")
+
+	var data map[string][]struct {
+		Count int64 `json:"count"`
+	}
+	if err := client.Query(query.String(), &data); err != nil {
+		return 0, nil, err
+	}
+
+	for _, c := range list {
+		var actual int64
+		for _, result := range data[c.alias] {
+			actual += result.Count
+		}
+		if actual != c.expected {
+			mismatches = append(mismatches, Mismatch{Check: c.name, Expected: c.expected, Actual: actual})
+		}
+	}
+	return len(list), mismatches, nil
+}
+
+// VerifyFiles compares the checksums of the dataset files of m with the files
+// on disk.
+func VerifyFiles(m *Manifest) error {
+	for _, file := range m.Files {
+		checksum, err := FileChecksum(file.Path)
+		if err != nil {
+			return err
+		}
+		if checksum != file.SHA256 {
+			return fmt.Errorf("%s has sha256 %s, the manifest lists %s", file.Path, checksum, file.SHA256)
+		}
+	}
+	return nil
+}
+
+func sortedKeys(counts map[string]int64) []string {
+	keys := make([]string, 0, len(counts))
+	for key := range counts {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
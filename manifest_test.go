@@ -0,0 +1,112 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestManifestFilesResolveAgainstManifest(t *testing.T) {
+	dir := t.TempDir()
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(wd)
+
+	if err := os.Mkdir("out", 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join("out", "d.rdf"), []byte("_:C1 <name> \"Ann\" .\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	m := NewManifest(DefaultScenario())
+	if err := m.AddFile(filepath.Join("out", "d.rdf"), ""); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Write(filepath.Join("out", "d.rdf.manifest.json")); err != nil {
+		t.Fatal(err)
+	}
+	if err := CommitOutputs(); err != nil {
+		t.Fatal(err)
+	}
+
+	var saved Manifest
+	data, _ := os.ReadFile(filepath.Join("out", "d.rdf.manifest.json"))
+	if err := json.Unmarshal(data, &saved); err != nil {
+		t.Fatal(err)
+	}
+	if saved.Files[0].Path != "d.rdf" {
+		t.Errorf("manifest stores %q, want the path relative to it", saved.Files[0].Path)
+	}
+
+	// Verify from another directory.
+	if err := os.Chdir(t.TempDir()); err != nil {
+		t.Fatal(err)
+	}
+	read, err := ReadManifest(filepath.Join(dir, "out", "d.rdf.manifest.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := VerifyFiles(read); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestVerifyCounts(t *testing.T) {
+	var query string
+	alpha := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, _ := io.ReadAll(r.Body)
+		query = string(data)
+		if r.URL.Path != "/query" || r.Header.Get("Content-Type") != "application/dql" {
+			http.Error(w, "unexpected request", http.StatusBadRequest)
+			return
+		}
+		w.Write([]byte(`{"data":{
+			"e0":[{"count":3}],"t0":[{"count":3}],
+			"e1":[{"count":5}],"t1":[{"count":4}],
+			"p0":[{"count":3}],"p1":[{"count":2}]}}`))
+	}))
+	defer alpha.Close()
+
+	m := NewManifest(DefaultScenario())
+	m.Entities = map[string]int64{EntityCustomer: 3, EntityInvoiceOrder: 5}
+	m.Edges = map[string]int64{"order": 5, "destination": 3}
+
+	checks, mismatches, err := VerifyCounts(NewClient(alpha.URL), m)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := `{
+  e0(func: eq(entity, "Customer")) { count: count(uid) }
+  t0(func: type(Customer)) { count: count(uid) }
+  e1(func: eq(entity, "Invoice Order")) { count: count(uid) }
+  t1(func: type(InvoiceOrder)) { count: count(uid) }
+  var(func: has(destination)) { c0 as count(destination) }
+  p0() { count: sum(val(c0)) }
+  var(func: has(order)) { c1 as count(order) }
+  p1() { count: sum(val(c1)) }
+}`
+	if query != want {
+		t.Errorf("query:\n%s\nwant:\n%s", query, want)
+	}
+	if checks != 6 {
+		t.Errorf("ran %d checks, want 6", checks)
+	}
+	wantMismatches := []Mismatch{
+		{Check: "dgraph.type InvoiceOrder", Expected: 5, Actual: 4},
+		{Check: "edges order", Expected: 5, Actual: 2},
+	}
+	if !reflect.DeepEqual(mismatches, wantMismatches) {
+		t.Errorf("mismatches = %v, want %v", mismatches, wantMismatches)
+	}
+}
@@ -0,0 +1,139 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"sync"
+	"syscall"
+)
+
+// Output is a dataset file written under a temporary name next to Path, and
+// renamed to Path by CommitOutputs once the whole dataset is written. A failed
+// or interrupted run removes it with AbortOutputs, so it never leaves a
+// partial dataset behind nor touches an existing one.
+type Output struct {
+	*os.File
+	Path string
+}
+
+var (
+	outputsMu sync.Mutex
+	outputs   []*Output // outputs not committed or aborted yet
+)
+
+// CreateOutput creates the temporary file of the output path.
+func CreateOutput(path string) (*Output, error) {
+	f, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return nil, err
+	}
+	if err := f.Chmod(0644); err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return nil, err
+	}
+
+	o := &Output{File: f, Path: path}
+	outputsMu.Lock()
+	outputs = append(outputs, o)
+	outputsMu.Unlock()
+	return o, nil
+}
+
+// WriteOutput writes data to a new output of path, committed with the others.
+func WriteOutput(path string, data []byte) error {
+	o, err := CreateOutput(path)
+	if err != nil {
+		return err
+	}
+	if _, err := o.Write(data); err != nil {
+		o.Close()
+		return err
+	}
+	return o.Close()
+}
+
+// PendingName returns the file holding the content of path: the temporary
+// file of its pending output, or path itself.
+func PendingName(path string) string {
+	outputsMu.Lock()
+	defer outputsMu.Unlock()
+
+	for _, o := range outputs {
+		if o.Path == path {
+			return o.Name()
+		}
+	}
+	return path
+}
+
+// CommitOutputs renames every pending output to its path. The outputs must
+// be closed.
+func CommitOutputs() error {
+	outputsMu.Lock()
+	defer outputsMu.Unlock()
+
+	for i, o := range outputs {
+		if err := os.Rename(o.Name(), o.Path); err != nil {
+			for _, o := range outputs[i:] {
+				os.Remove(o.Name())
+			}
+			outputs = nil
+			return err
+		}
+	}
+	outputs = nil
+	return nil
+}
+
+// AbortOutputs removes every pending output.
+func AbortOutputs() {
+	outputsMu.Lock()
+	defer outputsMu.Unlock()
+
+	for _, o := range outputs {
+		o.Close()
+		os.Remove(o.Name())
+	}
+	outputs = nil
+}
+
+// CheckOverwrite fails when any of paths exists, unless force is set.
+func CheckOverwrite(paths []string, force bool) error {
+	if force {
+		return nil
+	}
+	for _, path := range paths {
+		if _, err := os.Stat(path); err == nil {
+			return fmt.Errorf("%s exists, pass -force to overwrite it", path)
+		} else if !os.IsNotExist(err) {
+			return err
+		}
+	}
+	return nil
+}
+
+// AbortOutputsOnInterrupt removes the pending outputs and exits when the
+// process is interrupted, until the returned function is called.
+func AbortOutputsOnInterrupt() (stop func()) {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	done := make(chan struct{})
+
+	go func() {
+		select {
+		case sig := <-signals:
+			AbortOutputs()
+			log.Printf("%s: partial output removed", sig)
+			os.Exit(130)
+		case <-done:
+		}
+	}()
+	return func() {
+		signal.Stop(signals)
+		close(done)
+	}
+}
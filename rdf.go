@@ -0,0 +1,227 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// XML Schema datatypes understood by Dgraph.
+const (
+	TypeInt      = "xs:int"
+	TypeFloat    = "xs:float"
+	TypeDateTime = "xs:dateTime"
+)
+
+// Quad is a single statement of the dataset. Edges carry the key of the
+// target node in Object and optionally Facets, attributes carry their literal
+// in Value. Node keys are blank node labels such as C1, or UIDs such as 0x2a
+// once assigned.
+type Quad struct {
+	Subject   string
+	Predicate string
+	Object    string
+	Value     interface{}
+	Facets    []Facet
+}
+
+// IsEdge reports whether the quad points to another node.
+func (q Quad) IsEdge() bool {
+	return q.Object != ""
+}
+
+// FormatQuad encodes quad as a single N-Quad line without the trailing newline.
+func FormatQuad(quad Quad) string {
+	if quad.IsEdge() {
+		return fmt.Sprintf(`%s <%s> %s%s .`, FormatNode(quad.Subject), quad.Predicate, FormatNode(quad.Object), FormatFacets(quad.Facets))
+	}
+	return fmt.Sprintf(`%s <%s> %s%s .`, FormatNode(quad.Subject), quad.Predicate, FormatLiteral(quad.Value), FormatFacets(quad.Facets))
+}
+
+// FormatLiteral encodes value as a literal, annotated with its datatype when
+// the Go type maps to one: integers to xs:int, decimals to xs:float and times
+// to xs:dateTime. Everything else is written as a plain string.
+func FormatLiteral(value interface{}) string {
+	lexical, datatype := literalForm(value)
+	lexical = EscapeLiteral(lexical)
+	if datatype == "" {
+		return `"` + lexical + `"`
+	}
+	return `"` + lexical + `"^^<` + datatype + `>`
+}
+
+func literalForm(value interface{}) (lexical, datatype string) {
+	switch v := value.(type) {
+	case string:
+		return v, ""
+	case int:
+		return strconv.Itoa(v), TypeInt
+	case int64:
+		return strconv.FormatInt(v, 10), TypeInt
+	case decimal.Decimal:
+		return v.String(), TypeFloat
+	case time.Time:
+		return v.Format(time.RFC3339), TypeDateTime
+	default:
+		return fmt.Sprint(v), ""
+	}
+}
+
+// FormatNode encodes a node key as a blank node, or as an IRI when the key is
+// a UID.
+func FormatNode(key string) string {
+	if IsUID(key) {
+		return "<" + key + ">"
+	}
+	return "_:" + key
+}
+
+// IsUID reports whether key is a Dgraph UID such as 0x2a.
+func IsUID(key string) bool {
+	if len(key) < 3 || key[0] != '0' || (key[1] != 'x' && key[1] != 'X') {
+		return false
+	}
+	for _, c := range key[2:] {
+		if !strings.ContainsRune("0123456789abcdefABCDEF", c) {
+			return false
+		}
+	}
+	return true
+}
+
+// IsBlankLabel reports whether s can be used as a blank node label.
+func IsBlankLabel(s string) bool {
+	if s == "" {
+		return false
+	}
+	for i, c := range s {
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c == '_':
+		case c >= '0' && c <= '9', c == '-', c == '.':
+			if i == 0 {
+				return false
+			}
+		default:
+			return false
+		}
+	}
+	return s[len(s)-1] != '.'
+}
+
+var errUnterminated = errors.New("unterminated term")
+
+// ParseQuad parses one N-Quad line as written by the generator.
+func ParseQuad(line string) (quad Quad, err error) {
+	rest := strings.TrimSpace(line)
+
+	if quad.Subject, rest, err = parseNode(rest); err != nil {
+		return quad, fmt.Errorf("subject: %w", err)
+	}
+	if quad.Predicate, rest, err = parseIRI(rest); err != nil {
+		return quad, fmt.Errorf("predicate: %w", err)
+	}
+
+	if strings.HasPrefix(rest, `"`) {
+		if quad.Value, rest, err = parseLiteral(rest); err != nil {
+			return quad, fmt.Errorf("object: %w", err)
+		}
+	} else if quad.Object, rest, err = parseNode(rest); err != nil {
+		return quad, fmt.Errorf("object: %w", err)
+	}
+	if quad.Facets, rest, err = parseFacets(rest); err != nil {
+		return quad, fmt.Errorf("facets: %w", err)
+	}
+
+	if rest != "." {
+		return quad, fmt.Errorf("expected terminating '.', got %q", rest)
+	}
+	return
+}
+
+// parseNode accepts both blank nodes and the <A1> style IRIs of older datasets.
+func parseNode(s string) (key, rest string, err error) {
+	if !strings.HasPrefix(s, "_:") {
+		return parseIRI(s)
+	}
+
+	end := strings.IndexAny(s, " \t")
+	if end < 0 {
+		return "", s, errUnterminated
+	}
+	key = s[2:end]
+	if !IsBlankLabel(key) {
+		return "", s, fmt.Errorf("invalid blank node %q", s[:end])
+	}
+	return key, strings.TrimLeft(s[end:], " \t"), nil
+}
+
+func parseIRI(s string) (iri, rest string, err error) {
+	if !strings.HasPrefix(s, "<") {
+		return "", s, fmt.Errorf("expected '<' at %q", s)
+	}
+	end := strings.IndexByte(s, '>')
+	if end < 0 {
+		return "", s, errUnterminated
+	}
+	iri = s[1:end]
+	if iri == "" {
+		return "", s, errors.New("empty IRI")
+	}
+	return iri, strings.TrimLeft(s[end+1:], " \t"), nil
+}
+
+// parseLiteral parses a literal and its optional datatype or language tag.
+// Typed literals are decoded back into the Go type FormatLiteral takes.
+func parseLiteral(s string) (value interface{}, rest string, err error) {
+	end := -1
+	for i := 1; i < len(s) && end < 0; i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case '"':
+			end = i
+		}
+	}
+	if end < 0 {
+		return nil, s, errUnterminated
+	}
+	lexical, err := UnescapeLiteral(s[1:end])
+	if err != nil {
+		return nil, s, err
+	}
+	rest = s[end+1:]
+
+	var datatype string
+	switch {
+	case strings.HasPrefix(rest, "^^"):
+		if datatype, rest, err = parseIRI(rest[2:]); err != nil {
+			return nil, s, fmt.Errorf("datatype: %w", err)
+		}
+	case strings.HasPrefix(rest, "@"):
+		end := strings.IndexAny(rest, " \t")
+		if end < 0 {
+			return nil, s, errUnterminated
+		}
+		rest = rest[end:]
+	}
+	rest = strings.TrimLeft(rest, " \t")
+
+	switch datatype {
+	case TypeInt:
+		value, err = strconv.ParseInt(lexical, 10, 64)
+	case TypeFloat:
+		value, err = decimal.NewFromString(lexical)
+	case TypeDateTime:
+		value, err = time.Parse(time.RFC3339, lexical)
+	default:
+		value = lexical
+	}
+	if err != nil {
+		return nil, s, fmt.Errorf("invalid %s literal %q", datatype, lexical)
+	}
+	return value, rest, nil
+}
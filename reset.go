@@ -0,0 +1,48 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Reset modes, from the widest to the narrowest.
+const (
+	ResetAll       = "all"       // every predicate, type and the schema
+	ResetData      = "data"      // every node, keeping the schema
+	ResetPopulator = "populator" // the predicates and types of the populator's entities
+)
+
+// ResetOps returns the drop operations of a reset mode. In populator mode the
+// types are dropped before the predicates they list.
+func ResetOps(mode string) ([]DropOp, error) {
+	switch mode {
+	case ResetAll:
+		return []DropOp{{DropAll: true}}, nil
+	case ResetData:
+		return []DropOp{{DropOp: "DATA"}}, nil
+	case ResetPopulator:
+		var ops []DropOp
+		for _, entityType := range EntityTypes {
+			ops = append(ops, DropOp{DropOp: "TYPE", DropValue: TypeName(entityType.Entity)})
+		}
+		for _, predicate := range Predicates {
+			ops = append(ops, DropOp{DropOp: "ATTR", DropValue: predicate.Name})
+		}
+		return ops, nil
+	}
+	return nil, fmt.Errorf("unknown reset mode %q, expected %s, %s or %s", mode, ResetAll, ResetData, ResetPopulator)
+}
+
+// Confirm writes prompt to w and reports whether the next line read from r is
+// answer.
+func Confirm(r io.Reader, w io.Writer, prompt, answer string) bool {
+	fmt.Fprintf(w, "%s Type %q to continue: ", prompt, answer)
+	line, err := bufio.NewReader(r).ReadString('\n')
+	if err != nil && line == "" {
+		fmt.Fprintln(w)
+		return false
+	}
+	return strings.TrimSpace(line) == answer
+}
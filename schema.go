@@ -0,0 +1,108 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// PredicateSchema describes the type and indexes of one generated predicate.
+type PredicateSchema struct {
+	Name    string
+	Type    string // string, int, float, datetime or uid
+	List    bool
+	Indexes []string
+	Upsert  bool
+	Reverse bool
+	Count   bool
+}
+
+// Predicates is the schema of every predicate the generator writes. Value
+// types follow the literals of FormatLiteral: decimals are float, counts int.
+var Predicates = []PredicateSchema{
+	{Name: "xid", Type: "string", Indexes: []string{"exact"}, Upsert: true},
+	{Name: "name", Type: "string", Indexes: []string{"exact", "term"}},
+	{Name: Entity, Type: "string", Indexes: []string{"exact"}},
+
+	// Customer
+	{Name: "destination", Type: "uid", Reverse: true},
+	{Name: "order", Type: "uid", List: true, Reverse: true, Count: true},
+
+	// Product
+	{Name: "price", Type: "float", Indexes: []string{"float"}},
+	{Name: "commission_percentage", Type: "int", Indexes: []string{"int"}},
+	{Name: "commission_amount", Type: "float"},
+	{Name: "category", Type: "uid", Reverse: true},
+	{Name: "origin", Type: "uid", Reverse: true},
+
+	// Invoice Order
+	{Name: "purchase_date", Type: "datetime", Indexes: []string{"day"}},
+	{Name: "order_detail", Type: "uid", List: true, Reverse: true, Count: true},
+
+	// Order Detail
+	{Name: "order_amount", Type: "int"},
+	{Name: "order_product", Type: "uid", Reverse: true},
+}
+
+// EntityType lists the predicates carried by the nodes of one entity.
+type EntityType struct {
+	Entity     string
+	Predicates []string
+}
+
+// EntityTypes are rendered as DQL type blocks named after TypeName(Entity).
+var EntityTypes = []EntityType{
+	{Entity: EntityCity, Predicates: []string{"xid", "name", Entity}},
+	{Entity: EntityCategory, Predicates: []string{"xid", "name", Entity}},
+	{Entity: EntityCustomer, Predicates: []string{"xid", "name", Entity, "destination", "order"}},
+	{Entity: EntityProduct, Predicates: []string{"xid", "name", Entity, "price", "commission_percentage", "commission_amount", "category", "origin"}},
+	{Entity: EntityInvoiceOrder, Predicates: []string{"xid", Entity, "purchase_date", "order_detail"}},
+	{Entity: EntityOrderDetail, Predicates: []string{"xid", Entity, "order_amount", "order_product"}},
+}
+
+// String renders the predicate as a DQL schema line.
+func (p PredicateSchema) String() string {
+	var b strings.Builder
+	b.WriteString(p.Name + ": ")
+	if p.List {
+		b.WriteString("[" + p.Type + "]")
+	} else {
+		b.WriteString(p.Type)
+	}
+	if len(p.Indexes) > 0 {
+		b.WriteString(" @index(" + strings.Join(p.Indexes, ", ") + ")")
+	}
+	if p.Upsert {
+		b.WriteString(" @upsert")
+	}
+	if p.Count {
+		b.WriteString(" @count")
+	}
+	if p.Reverse {
+		b.WriteString(" @reverse")
+	}
+	b.WriteString(" .")
+	return b.String()
+}
+
+// String renders the entity as a DQL type block.
+func (t EntityType) String() string {
+	var b strings.Builder
+	b.WriteString("type " + TypeName(t.Entity) + " {\n")
+	for _, predicate := range t.Predicates {
+		b.WriteString("  " + predicate + "\n")
+	}
+	b.WriteString("}")
+	return b.String()
+}
+
+// GenerateSchema returns the DQL schema of the generated dataset.
+func GenerateSchema() string {
+	var b strings.Builder
+	for _, predicate := range Predicates {
+		fmt.Fprintln(&b, predicate)
+	}
+	for _, entityType := range EntityTypes {
+		fmt.Fprintf(&b, "\n%s\n", entityType)
+	}
+	return b.String()
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+)
+
+// UIDMap maps blank node labels to the UIDs Dgraph assigned them. When backed
+// by a file, every assignment is appended to it as a "label uid" line, so a
+// later load can link new quads to nodes committed by an earlier one.
+type UIDMap struct {
+	mu   sync.RWMutex
+	uids map[string]string
+
+	file *os.File
+	buf  *bufio.Writer
+}
+
+// NewUIDMap returns an empty map kept in memory only.
+func NewUIDMap() *UIDMap {
+	return &UIDMap{uids: make(map[string]string)}
+}
+
+// OpenUIDMap loads the map file at path, creating it when missing, and keeps
+// it open to append new assignments.
+func OpenUIDMap(path string) (*UIDMap, error) {
+	m := NewUIDMap()
+
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, err
+	}
+
+	scanner := bufio.NewScanner(f)
+	lineNo := 0
+	for scanner.Scan() {
+		lineNo++
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 {
+			continue
+		}
+		if len(fields) != 2 || !IsUID(fields[1]) {
+			f.Close()
+			return nil, fmt.Errorf("%s:%d: expected \"label uid\"", path, lineNo)
+		}
+		m.uids[fields[0]] = fields[1]
+	}
+	if err := scanner.Err(); err != nil {
+		f.Close()
+		return nil, err
+	}
+
+	m.file = f
+	m.buf = bufio.NewWriter(f)
+	return m, nil
+}
+
+// Len returns the number of known labels.
+func (m *UIDMap) Len() int {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return len(m.uids)
+}
+
+// Get returns the UID assigned to label.
+func (m *UIDMap) Get(label string) (uid string, ok bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	uid, ok = m.uids[label]
+	return
+}
+
+// Add records the uids map of a mutation response.
+func (m *UIDMap) Add(uids map[string]string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for label, uid := range uids {
+		m.uids[label] = uid
+		if m.buf != nil {
+			fmt.Fprintf(m.buf, "%s %s\n", label, uid)
+		}
+	}
+	if m.buf != nil {
+		return m.buf.Flush()
+	}
+	return nil
+}
+
+// Close flushes and closes the map file.
+func (m *UIDMap) Close() error {
+	if m.file == nil {
+		return nil
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	err := m.buf.Flush()
+	if closeErr := m.file.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ReferenceEntities are matched by name in upsert mode. Their XIDs are minted
+// per dataset, while their names identify them across datasets.
+var ReferenceEntities = map[string]bool{
+	TypeName(EntityCity):     true,
+	TypeName(EntityCategory): true,
+}
+
+// nodeKey is what an upsert matches an existing node on.
+type nodeKey struct {
+	XID  string
+	Name string
+	Type string
+}
+
+// ready reports whether enough of the node is known to look it up.
+func (k *nodeKey) ready() bool {
+	return k.Type != "" && (k.XID != "" || k.Name != "")
+}
+
+// byName reports whether the node is looked up by name rather than by xid:
+// reference entities, and nodes without a known XID.
+func (k *nodeKey) byName() bool {
+	return k.Name != "" && (k.XID == "" || ReferenceEntities[k.Type])
+}
+
+// query returns the upsert query block line binding variable v to the node.
+func (k *nodeKey) query(v string) string {
+	if k.byName() {
+		return fmt.Sprintf(`%s as var(func: eq(name, "%s"), first: 1) @filter(type(%s))`, v, EscapeLiteral(k.Name), k.Type)
+	}
+	return fmt.Sprintf(`%s as var(func: eq(xid, "%s"), first: 1)`, v, EscapeLiteral(k.XID))
+}
+
+// observe records the quad when it carries part of its subject's key.
+func (k *nodeKey) observe(quad Quad) {
+	switch quad.Predicate {
+	case "xid":
+		k.XID = fmt.Sprint(quad.Value)
+	case "name":
+		k.Name = fmt.Sprint(quad.Value)
+	case DgraphType:
+		k.Type = fmt.Sprint(quad.Value)
+	}
+}
+
+// buildUpsert turns quads into the query and set blocks of an upsert, with
+// every blank node replaced by a uid() of a variable matching it on keys.
+func buildUpsert(quads []Quad, keys map[string]*nodeKey) (query, rdf string) {
+	vars := make(map[string]string)
+	var q, set strings.Builder
+
+	bind := func(label string) string {
+		if IsUID(label) {
+			return "<" + label + ">"
+		}
+		v, ok := vars[label]
+		if !ok {
+			v = fmt.Sprintf("v%d", len(vars))
+			vars[label] = v
+			q.WriteString("    " + keys[label].query(v) + "\n")
+		}
+		return "uid(" + v + ")"
+	}
+
+	for _, quad := range quads {
+		subject := bind(quad.Subject)
+		object := FormatLiteral(quad.Value)
+		if quad.IsEdge() {
+			object = bind(quad.Object)
+		}
+		fmt.Fprintf(&set, "%s <%s> %s%s .\n", subject, quad.Predicate, object, FormatFacets(quad.Facets))
+	}
+	return q.String(), set.String()
+}
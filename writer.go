@@ -0,0 +1,359 @@
+package main
+
+import (
+	"bufio"
+	"compress/gzip"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// DatasetWriter receives every quad of a generated dataset. A writer is opened
+// once, buffers its output, and must be closed to flush it.
+type DatasetWriter interface {
+	WriteQuad(quad Quad) error
+	Flush() error
+	Close() error
+}
+
+// Output formats of a dataset.
+const (
+	FormatRDF  = "rdf"  // N-Quads
+	FormatJSON = "json" // Dgraph JSON mutation objects
+)
+
+// OpenDatasetFile creates the Output of path and returns a writer encoding
+// quads in format into it. Paths ending in .gz are gzip compressed at
+// gzipLevel.
+func OpenDatasetFile(path, format string, gzipLevel int) (DatasetWriter, error) {
+	if format != FormatRDF && format != FormatJSON {
+		return nil, fmt.Errorf("unsupported format %q", format)
+	}
+	if IsGzipPath(path) && (gzipLevel < gzip.HuffmanOnly || gzipLevel > gzip.BestCompression) {
+		return nil, fmt.Errorf("invalid gzip level %d", gzipLevel)
+	}
+
+	f, err := CreateOutput(path)
+	if err != nil {
+		return nil, err
+	}
+	var dst io.WriteCloser = f
+	if IsGzipPath(path) {
+		if dst, err = NewGzipWriter(f, gzipLevel); err != nil {
+			f.Close()
+			return nil, err
+		}
+	}
+	if format == FormatJSON {
+		return NewJSONWriter(dst), nil
+	}
+	return NewRDFWriter(dst), nil
+}
+
+// Shard assignments of a ShardedWriter.
+const (
+	ShardBySubject  = "subject"     // every quad of a node in the same shard
+	ShardRoundRobin = "round-robin" // quads dealt over the shards in turn
+)
+
+// ShardPaths returns the paths of n shards of the dataset path, numbered
+// before its extension: dataset.rdf.gz becomes dataset.0.rdf.gz and so on.
+func ShardPaths(path string, n int) []string {
+	stem, ext := splitDatasetPath(path)
+	width := len(strconv.Itoa(n - 1))
+	paths := make([]string, n)
+	for i := range paths {
+		paths[i] = fmt.Sprintf("%s.%0*d%s", stem, width, i, ext)
+	}
+	return paths
+}
+
+// SchemaPath returns the schema file kept next to the dataset path:
+// dataset.rdf.gz becomes dataset.schema.
+func SchemaPath(path string) string {
+	stem, _ := splitDatasetPath(path)
+	return stem + ".schema"
+}
+
+// splitDatasetPath splits path into its stem and its extension, including a
+// trailing .gz.
+func splitDatasetPath(path string) (stem, ext string) {
+	stem = path
+	if IsGzipPath(stem) {
+		stem, ext = strings.TrimSuffix(stem, ".gz"), ".gz"
+	}
+	if e := filepath.Ext(stem); e != "" {
+		stem, ext = strings.TrimSuffix(stem, e), e+ext
+	}
+	return stem, ext
+}
+
+// ShardedWriter spreads quads over several writers, so a bulk load can read
+// the shards in parallel.
+type ShardedWriter struct {
+	Shards []DatasetWriter
+	By     string // ShardBySubject or ShardRoundRobin
+	next   int
+}
+
+// OpenShardedDataset opens a dataset file in format for every path.
+func OpenShardedDataset(paths []string, format string, gzipLevel int, by string) (*ShardedWriter, error) {
+	if by != ShardBySubject && by != ShardRoundRobin {
+		return nil, fmt.Errorf("unsupported sharding %q", by)
+	}
+
+	sw := &ShardedWriter{By: by}
+	for _, path := range paths {
+		w, err := OpenDatasetFile(path, format, gzipLevel)
+		if err != nil {
+			sw.Close()
+			return nil, err
+		}
+		sw.Shards = append(sw.Shards, w)
+	}
+	return sw, nil
+}
+
+// WriteQuad implements DatasetWriter.
+func (sw *ShardedWriter) WriteQuad(quad Quad) error {
+	var shard int
+	if sw.By == ShardBySubject {
+		h := fnv.New32a()
+		h.Write([]byte(quad.Subject))
+		shard = int(h.Sum32() % uint32(len(sw.Shards)))
+	} else {
+		shard = sw.next
+		sw.next = (sw.next + 1) % len(sw.Shards)
+	}
+	return sw.Shards[shard].WriteQuad(quad)
+}
+
+// Flush implements DatasetWriter.
+func (sw *ShardedWriter) Flush() error {
+	for _, w := range sw.Shards {
+		if err := w.Flush(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// StartPhase implements PhaseWriter.
+func (sw *ShardedWriter) StartPhase(phase string) error {
+	for _, w := range sw.Shards {
+		if err := startPhase(w, phase); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Close implements DatasetWriter. It closes every shard and returns the first
+// error.
+func (sw *ShardedWriter) Close() error {
+	var err error
+	for _, w := range sw.Shards {
+		if closeErr := w.Close(); err == nil {
+			err = closeErr
+		}
+	}
+	return err
+}
+
+// IsGzipPath reports whether path names a gzip compressed dataset.
+func IsGzipPath(path string) bool {
+	return strings.HasSuffix(path, ".gz")
+}
+
+// GzipWriter compresses into an underlying writer. Closing it finishes the
+// gzip stream and then closes the underlying writer.
+type GzipWriter struct {
+	*gzip.Writer
+	dst io.WriteCloser
+}
+
+// NewGzipWriter returns a GzipWriter compressing into dst at level, one of the
+// compress/gzip levels.
+func NewGzipWriter(dst io.WriteCloser, level int) (*GzipWriter, error) {
+	gz, err := gzip.NewWriterLevel(dst, level)
+	if err != nil {
+		return nil, err
+	}
+	return &GzipWriter{Writer: gz, dst: dst}, nil
+}
+
+// Close flushes the gzip footer and closes the underlying writer.
+func (gw *GzipWriter) Close() error {
+	err := gw.Writer.Close()
+	if closeErr := gw.dst.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// PhaseWriter is a DatasetWriter told when Generate moves on to the next of
+// its Phases, such as one writing every entity to its own file.
+type PhaseWriter interface {
+	DatasetWriter
+	StartPhase(phase string) error
+}
+
+// startPhase tells w that phase starts, when w is a PhaseWriter.
+func startPhase(w DatasetWriter, phase string) error {
+	if pw, ok := w.(PhaseWriter); ok {
+		return pw.StartPhase(phase)
+	}
+	return nil
+}
+
+// EntityWriter writes every phase of the dataset to its own writer.
+type EntityWriter struct {
+	Phases  map[string]DatasetWriter
+	current DatasetWriter
+}
+
+// EntityPaths returns the per-entity files of the dataset path, named after
+// the Phases next to it: dataset.rdf.gz gives cities.rdf.gz and so on.
+func EntityPaths(path string) map[string]string {
+	stem, ext := splitDatasetPath(path)
+	dir := filepath.Dir(stem)
+	paths := make(map[string]string, len(Phases))
+	for _, phase := range Phases {
+		paths[phase] = filepath.Join(dir, phase+ext)
+	}
+	return paths
+}
+
+// OpenEntityDataset opens a dataset file in format for every phase of paths.
+func OpenEntityDataset(paths map[string]string, format string, gzipLevel int) (*EntityWriter, error) {
+	ew := &EntityWriter{Phases: make(map[string]DatasetWriter, len(paths))}
+	for _, phase := range Phases {
+		w, err := OpenDatasetFile(paths[phase], format, gzipLevel)
+		if err != nil {
+			ew.Close()
+			return nil, err
+		}
+		ew.Phases[phase] = w
+	}
+	return ew, nil
+}
+
+// StartPhase implements PhaseWriter. The file of the previous phase is
+// complete and flushed.
+func (ew *EntityWriter) StartPhase(phase string) error {
+	w, ok := ew.Phases[phase]
+	if !ok {
+		return fmt.Errorf("no file for phase %q", phase)
+	}
+	if ew.current != nil {
+		if err := ew.current.Flush(); err != nil {
+			return err
+		}
+	}
+	ew.current = w
+	return nil
+}
+
+// WriteQuad implements DatasetWriter.
+func (ew *EntityWriter) WriteQuad(quad Quad) error {
+	if ew.current == nil {
+		return fmt.Errorf("quad written before any phase: %s", FormatQuad(quad))
+	}
+	return ew.current.WriteQuad(quad)
+}
+
+// Flush implements DatasetWriter.
+func (ew *EntityWriter) Flush() error {
+	for _, phase := range Phases {
+		if w, ok := ew.Phases[phase]; ok {
+			if err := w.Flush(); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Close implements DatasetWriter. It closes every file and returns the first
+// error.
+func (ew *EntityWriter) Close() error {
+	var err error
+	for _, phase := range Phases {
+		if w, ok := ew.Phases[phase]; ok {
+			if closeErr := w.Close(); err == nil {
+				err = closeErr
+			}
+		}
+	}
+	return err
+}
+
+// PrefixWriter prepends a per-run prefix to every node key, so blank nodes of
+// separate incremental loads never collide.
+type PrefixWriter struct {
+	DatasetWriter
+	Prefix string
+}
+
+// WriteQuad implements DatasetWriter.
+func (pw PrefixWriter) WriteQuad(quad Quad) error {
+	quad.Subject = pw.Prefix + quad.Subject
+	if quad.IsEdge() {
+		quad.Object = pw.Prefix + quad.Object
+	}
+	return pw.DatasetWriter.WriteQuad(quad)
+}
+
+// StartPhase implements PhaseWriter.
+func (pw PrefixWriter) StartPhase(phase string) error {
+	return startPhase(pw.DatasetWriter, phase)
+}
+
+// writeQuads writes quads in order and stops at the first error.
+func writeQuads(w DatasetWriter, quads ...Quad) error {
+	for _, quad := range quads {
+		if err := w.WriteQuad(quad); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// RDFWriter encodes quads as N-Quad lines.
+type RDFWriter struct {
+	buf    *bufio.Writer
+	closer io.Closer
+}
+
+// NewRDFWriter returns a buffered N-Quad writer on top of w. Closing the
+// writer also closes w when it is an io.Closer.
+func NewRDFWriter(w io.Writer) *RDFWriter {
+	rw := &RDFWriter{buf: bufio.NewWriterSize(w, 256*1024)}
+	rw.closer, _ = w.(io.Closer)
+	return rw
+}
+
+// WriteQuad implements DatasetWriter.
+func (rw *RDFWriter) WriteQuad(quad Quad) error {
+	_, err := rw.buf.WriteString(FormatQuad(quad) + "\n")
+	return err
+}
+
+// Flush implements DatasetWriter.
+func (rw *RDFWriter) Flush() error {
+	return rw.buf.Flush()
+}
+
+// Close implements DatasetWriter.
+func (rw *RDFWriter) Close() error {
+	err := rw.buf.Flush()
+	if rw.closer != nil {
+		if closeErr := rw.closer.Close(); err == nil {
+			err = closeErr
+		}
+	}
+	return err
+}